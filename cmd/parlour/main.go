@@ -51,6 +51,13 @@ func main() {
 	encKey := getKey("PARLOUR_SESSION_ENC_KEY")
 	store := cookie.NewStore(authKey, encKey)
 	p := parlour.New(roomRepository, store)
+	p.SetAccountRepository(parlour.NewPostgresAccountRepository(pool))
+	p.SetTokenRepository(parlour.NewPostgresTokenRepository(pool))
+	p.SetRatingRepository(parlour.NewPostgresRatingRepository(pool))
+	p.SetTournamentRepository(parlour.NewPostgresTournamentRepository(pool))
+	if os.Getenv("PARLOUR_INVITE_KEY") != "" {
+		p.SetInviteKey(getKey("PARLOUR_INVITE_KEY"))
+	}
 	err = p.Run(host + ":" + port)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
@@ -23,6 +23,11 @@ const (
 	EventEnd     = "end"
 	EventFlower  = "flower"
 	EventBitten  = "bitten"
+	EventVoid    = "void"
+
+	// EventConcealedGang is a gang formed entirely from tiles in hand. Its
+	// tiles are hidden from other players until the round is over.
+	EventConcealedGang = "concealed_gang"
 )
 
 // Event represents a player's view of an event.
@@ -48,3 +53,33 @@ func newEvent(eventType EventType, seat int, t time.Time, tiles ...Tile) Event {
 		Tiles: tiles,
 	}
 }
+
+// ScoreReason represents the reason for an immediate payment.
+type ScoreReason string
+
+// Possible score reasons.
+const (
+	ScoreBitten        ScoreReason = "bitten"
+	ScoreExposedGang   ScoreReason = "exposed_gang"
+	ScoreConcealedGang ScoreReason = "concealed_gang"
+	ScoreFlowerSet     ScoreReason = "flower_set"
+)
+
+// ScoreEvent represents an immediate payment from one player to another which
+// happened during a round.
+type ScoreEvent struct {
+	// Reason is the reason for a payment.
+	Reason ScoreReason `json:"reason"`
+
+	// Payer is the integer offset of the player who paid.
+	Payer int `json:"payer"`
+
+	// Payee is the integer offset of the player who was paid.
+	Payee int `json:"payee"`
+
+	// Amount is how much was paid.
+	Amount int `json:"amount"`
+
+	// Time is the time a payment was made.
+	Time int64 `json:"time"`
+}
@@ -1,5 +1,7 @@
 package mahjong
 
+import "encoding/json"
+
 // MeldType represents the type of a melded set.
 type MeldType int
 
@@ -15,6 +17,40 @@ const (
 type Meld struct {
 	Type  MeldType `json:"type"`
 	Tiles []Tile   `json:"tiles"`
+
+	// Concealed indicates whether a meld was formed entirely from tiles in
+	// hand, such as a concealed gang.
+	Concealed bool `json:"concealed,omitempty"`
+
+	// From is the integer offset of the player whose discard was claimed to
+	// form a meld, or -1 if the meld was formed concealed.
+	From int `json:"from"`
+}
+
+// faceDown returns a meld as seen by other players before showdown.
+func (m Meld) faceDown() Meld {
+	if !m.Concealed {
+		return m
+	}
+	return Meld{
+		Type:      m.Type,
+		Tiles:     make([]Tile, len(m.Tiles)),
+		Concealed: true,
+		From:      m.From,
+	}
+}
+
+// UnmarshalJSON decodes a meld. Melds saved before the claimed player was
+// recorded are not from any player.
+func (m *Meld) UnmarshalJSON(data []byte) error {
+	type meld Meld
+	decoded := meld{From: -1}
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*m = Meld(decoded)
+	return nil
 }
 
 type Melds []Meld
@@ -116,16 +152,34 @@ type Hand struct {
 	Finished  []Tile  `json:"finished,omitempty"`
 }
 
-// View returns another player's view of a hand.
+// View returns another player's view of a hand. Concealed melds are shown
+// face down.
 func (h Hand) View() Hand {
+	var revealed Melds
+	if h.Revealed != nil {
+		revealed = make(Melds, len(h.Revealed))
+		for i, meld := range h.Revealed {
+			revealed[i] = meld.faceDown()
+		}
+	}
 	return Hand{
 		Flowers:   h.Flowers,
-		Revealed:  h.Revealed,
+		Revealed:  revealed,
 		Concealed: TileBag{"": h.Concealed.Cardinality()},
 		Finished:  h.Finished,
 	}
 }
 
+// isConcealed reports whether a hand has no melds formed from discards.
+func (h Hand) isConcealed() bool {
+	for _, meld := range h.Revealed {
+		if !meld.Concealed {
+			return false
+		}
+	}
+	return true
+}
+
 // Direction represents a wind direction.
 type Direction int
 
@@ -150,6 +204,12 @@ const (
 	// is has 14 tiles in their hand and must discard a tile. They may also
 	// reveal a concealed gang or win by self-draw.
 	PhaseDiscard Phase = "discard"
+
+	// PhaseRobGang represents the window after a player promotes a pong to a
+	// gang, when any other player may rob the gang by winning on the added
+	// tile. It ends when the player who declared the gang draws a
+	// replacement tile.
+	PhaseRobGang Phase = "rob_gang"
 )
 
 // Result represents the outcome of a round.
@@ -169,6 +229,33 @@ type Result struct {
 	// Points is how much the winning hand was worth.
 	Points int `json:"points"`
 
+	// Breakdown contains the scoring elements which made up Points.
+	Breakdown []Tai `json:"breakdown,omitempty"`
+
+	// Liable is the integer offset of the player who pays on behalf of
+	// everyone for feeding the winner a limit hand, or -1 if there is none.
+	Liable int `json:"liable"`
+
+	// Payments contains how much each player's score changed as a result of
+	// the win.
+	Payments [4]int `json:"payments"`
+
+	// Voided indicates that a round was cancelled and its payments undone.
+	Voided bool `json:"voided,omitempty"`
+
 	// WinningTiles is the set of flowers and tiles belonging to the winner.
 	WinningTiles []Tile `json:"winning_tiles"`
 }
+
+// UnmarshalJSON decodes a result. Results saved before liability was recorded
+// have no liable player.
+func (r *Result) UnmarshalJSON(data []byte) error {
+	type result Result
+	decoded := result{Liable: -1}
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*r = Result(decoded)
+	return nil
+}
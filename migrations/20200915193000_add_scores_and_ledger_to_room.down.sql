@@ -0,0 +1,3 @@
+alter table rooms
+    drop column scores,
+    drop column ledger;
@@ -0,0 +1,3 @@
+alter table rooms
+    add column scores jsonb,
+    add column ledger jsonb;
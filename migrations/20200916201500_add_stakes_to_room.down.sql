@@ -0,0 +1,2 @@
+alter table rooms
+    drop column stakes;
@@ -0,0 +1,3 @@
+alter table rooms
+    add column host   text,
+    add column locked boolean;
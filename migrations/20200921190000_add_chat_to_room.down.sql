@@ -0,0 +1,3 @@
+alter table rooms
+    drop column chat,
+    drop column muted;
@@ -0,0 +1,3 @@
+alter table rooms
+    add column chat  jsonb,
+    add column muted jsonb;
@@ -0,0 +1,3 @@
+alter table rooms
+    add column public  boolean not null default false,
+    add column ruleset text;
@@ -0,0 +1,2 @@
+alter table rooms
+    add column winds int not null default 0;
@@ -0,0 +1,7 @@
+create table accounts
+(
+    id            text primary key,
+    username      text        not null unique,
+    password_hash bytea       not null,
+    created_at    timestamptz not null
+);
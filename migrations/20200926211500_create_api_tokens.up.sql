@@ -0,0 +1,8 @@
+create table api_tokens
+(
+    id         text primary key,
+    player_id  text        not null,
+    name       text        not null,
+    hash       bytea       not null unique,
+    created_at timestamptz not null
+);
@@ -0,0 +1,8 @@
+create table ratings
+(
+    username text not null,
+    ruleset  text not null,
+    rating   int  not null,
+    games    int  not null,
+    primary key (username, ruleset)
+);
@@ -0,0 +1,2 @@
+alter table rooms
+    drop column rating_changes;
@@ -0,0 +1,2 @@
+alter table rooms
+    add column rating_changes jsonb;
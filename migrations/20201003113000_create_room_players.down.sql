@@ -0,0 +1 @@
+drop table room_players;
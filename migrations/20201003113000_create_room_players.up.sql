@@ -0,0 +1,16 @@
+create table room_players
+(
+    room_id   text        not null references rooms (id),
+    player_id text        not null,
+    joined_at timestamptz not null,
+    primary key (room_id, player_id)
+);
+
+create index room_players_player_id_joined_at_idx on room_players (player_id, joined_at desc);
+
+insert into room_players (room_id, player_id, joined_at)
+select rooms.id, player ->> 'id', now()
+from rooms,
+     jsonb_array_elements(rooms.players) player
+where not coalesce((player ->> 'is_bot')::boolean, false)
+on conflict do nothing;
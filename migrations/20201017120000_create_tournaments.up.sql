@@ -0,0 +1,9 @@
+create table tournaments
+(
+    id        text primary key,
+    organiser text  not null,
+    settings  jsonb not null,
+    phase     text  not null,
+    entrants  jsonb not null,
+    sessions  jsonb not null
+);
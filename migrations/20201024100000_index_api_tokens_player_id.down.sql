@@ -0,0 +1 @@
+drop index api_tokens_player_id_idx;
@@ -0,0 +1 @@
+create index api_tokens_player_id_idx on api_tokens (player_id);
@@ -0,0 +1,123 @@
+package parlour
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+type AccountRepository interface {
+	Create(account *Account) error
+	GetByUsername(username string) (*Account, error)
+	GetByID(id string) (*Account, error)
+}
+
+type InMemoryAccountRepository struct {
+	sync.RWMutex
+	accounts map[string]*Account
+}
+
+func NewInMemoryAccountRepository() *InMemoryAccountRepository {
+	return &InMemoryAccountRepository{
+		accounts: map[string]*Account{},
+	}
+}
+
+func (r *InMemoryAccountRepository) Create(account *Account) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.accounts[account.Username]; exists {
+		return errUsernameTaken
+	}
+	for _, a := range r.accounts {
+		if a.ID == account.ID {
+			return errors.New("account already exists")
+		}
+	}
+	r.accounts[account.Username] = account
+	return nil
+}
+
+func (r *InMemoryAccountRepository) GetByUsername(username string) (*Account, error) {
+	r.RLock()
+	defer r.RUnlock()
+	account, ok := r.accounts[username]
+	if !ok {
+		return nil, errNotFound
+	}
+	return account, nil
+}
+
+func (r *InMemoryAccountRepository) GetByID(id string) (*Account, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, account := range r.accounts {
+		if account.ID == id {
+			return account, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+type PostgresAccountRepository struct {
+	conn Conn
+}
+
+func (p *PostgresAccountRepository) Create(account *Account) error {
+	_, err := p.conn.Exec(context.Background(),
+		"insert into accounts (id, username, password_hash, created_at) values ($1, $2, $3, $4)",
+		account.ID,
+		account.Username,
+		account.PasswordHash,
+		account.CreatedAt,
+	)
+	var pgError *pgconn.PgError
+	if errors.As(err, &pgError) && pgError.Code == pgerrcode.UniqueViolation && pgError.ConstraintName == "accounts_username_key" {
+		return errUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("error creating account: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresAccountRepository) GetByUsername(username string) (*Account, error) {
+	var account Account
+	err := p.conn.QueryRow(
+		context.Background(),
+		"select id, username, password_hash, created_at from accounts where username = $1", username,
+	).Scan(&account.ID, &account.Username, &account.PasswordHash, &account.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting account: %w", err)
+	}
+	return &account, nil
+}
+
+func (p *PostgresAccountRepository) GetByID(id string) (*Account, error) {
+	var account Account
+	err := p.conn.QueryRow(
+		context.Background(),
+		"select id, username, password_hash, created_at from accounts where id = $1", id,
+	).Scan(&account.ID, &account.Username, &account.PasswordHash, &account.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting account: %w", err)
+	}
+	return &account, nil
+}
+
+func NewPostgresAccountRepository(conn Conn) *PostgresAccountRepository {
+	return &PostgresAccountRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,112 @@
+package parlour
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errUsernameTaken      = errors.New("username taken")
+	errInvalidCredentials = errors.New("invalid username or password")
+	errAlreadySignedIn    = errors.New("already signed in")
+	errTooManySignIns     = errors.New("too many sign in attempts")
+
+	usernamePattern = regexp.MustCompile("^[a-z0-9_]{3,20}$")
+)
+
+// Sign in attempts for each username are limited to SignInAttempts within
+// SignInWindow.
+const (
+	SignInAttempts = 5
+	SignInWindow   = time.Minute
+)
+
+// Account represents a registered player. The ID of an account is used as the
+// player ID of whoever is signed in to it.
+type Account struct {
+	ID           string
+	Username     string
+	PasswordHash []byte
+	CreatedAt    time.Time
+}
+
+type accountService struct {
+	AccountRepository AccountRepository
+
+	// hashCost is the bcrypt cost used to hash passwords.
+	hashCost int
+
+	signInLimiter *rateLimiter
+}
+
+func newAccountService(accountRepository AccountRepository) *accountService {
+	return &accountService{
+		AccountRepository: accountRepository,
+		hashCost:          bcrypt.DefaultCost,
+		signInLimiter:     newRateLimiter(SignInAttempts, SignInWindow),
+	}
+}
+
+func validateCredentials(username, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("username must be 3 to 20 lowercase letters, digits or underscores")
+	}
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+	if len(password) > 72 {
+		return errors.New("password must be at most 72 characters")
+	}
+	return nil
+}
+
+// Register creates an account for the guest playerID. The account keeps the
+// guest's player ID, so rooms the guest played in carry over to the account.
+func (s *accountService) Register(playerID, username, password string) (*Account, error) {
+	username = strings.ToLower(username)
+	if err := validateCredentials(username, password); err != nil {
+		return nil, &Error{error: err}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.hashCost)
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	account := &Account{
+		ID:           playerID,
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+	err = s.AccountRepository.Create(account)
+	if errors.Is(err, errUsernameTaken) {
+		return nil, &Error{error: err}
+	}
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	return account, nil
+}
+
+// Authenticate returns the account with username if password is correct.
+func (s *accountService) Authenticate(username, password string) (*Account, error) {
+	username = strings.ToLower(username)
+	if !s.signInLimiter.allow(username, time.Now()) {
+		return nil, &Error{error: errTooManySignIns}
+	}
+	account, err := s.AccountRepository.GetByUsername(username)
+	if errors.Is(err, errNotFound) {
+		return nil, &Error{error: errInvalidCredentials}
+	}
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	err = bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password))
+	if err != nil {
+		return nil, &Error{error: errInvalidCredentials}
+	}
+	return account, nil
+}
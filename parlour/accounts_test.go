@@ -0,0 +1,68 @@
+package parlour
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAccountService() *accountService {
+	s := newAccountService(NewInMemoryAccountRepository())
+	s.hashCost = bcrypt.MinCost
+	return s
+}
+
+func Test_accountService_Register(t *testing.T) {
+	t.Run("invalid username", func(t *testing.T) {
+		s := newTestAccountService()
+		_, err := s.Register("id1", "a", "password")
+		assert.EqualError(t, err, "username must be 3 to 20 lowercase letters, digits or underscores")
+	})
+	t.Run("password too short", func(t *testing.T) {
+		s := newTestAccountService()
+		_, err := s.Register("id1", "alice", "pass")
+		assert.EqualError(t, err, "password must be at least 8 characters")
+	})
+	t.Run("username taken", func(t *testing.T) {
+		s := newTestAccountService()
+		_, _ = s.Register("id1", "alice", "password")
+		_, err := s.Register("id2", "Alice", "password")
+		assert.EqualError(t, err, "username taken")
+	})
+	t.Run("keeps guest player ID", func(t *testing.T) {
+		s := newTestAccountService()
+		account, err := s.Register("id1", "Alice", "password")
+		assert.NoError(t, err)
+		assert.Equal(t, "id1", account.ID)
+		assert.Equal(t, "alice", account.Username)
+		assert.NotEqual(t, []byte("password"), account.PasswordHash)
+	})
+}
+
+func Test_accountService_Authenticate(t *testing.T) {
+	s := newTestAccountService()
+	_, _ = s.Register("id1", "alice", "password")
+
+	_, err := s.Authenticate("bob", "password")
+	assert.EqualError(t, err, "invalid username or password")
+	_, err = s.Authenticate("alice", "wrong password")
+	assert.EqualError(t, err, "invalid username or password")
+
+	account, err := s.Authenticate("ALICE", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "id1", account.ID)
+}
+
+func Test_accountService_Authenticate_rateLimit(t *testing.T) {
+	s := newTestAccountService()
+	_, _ = s.Register("id1", "alice", "password")
+	for i := 0; i < SignInAttempts; i++ {
+		_, _ = s.Authenticate("alice", "wrong password")
+	}
+	_, err := s.Authenticate("ALICE", "password")
+	assert.EqualError(t, err, "too many sign in attempts")
+
+	_, err = s.Authenticate("bob", "password")
+	assert.EqualError(t, err, "invalid username or password", "other usernames should not be limited")
+}
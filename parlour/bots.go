@@ -20,7 +20,7 @@ func (ai discardRandomTileAI) Think(view RoomView) *Action {
 	if view.Round.Turn != view.Round.Seat {
 		return nil
 	}
-	if view.Round.Phase == mahjong.PhaseDraw {
+	if view.Round.Phase == mahjong.PhaseDraw || view.Round.Phase == mahjong.PhaseRobGang {
 		time.Sleep(time.Duration(view.Round.ReservedDuration)*time.Millisecond + time.Second)
 		return &Action{
 			Nonce: view.Nonce,
@@ -0,0 +1,141 @@
+package parlour
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxChatHistory is the number of chat messages kept in a room.
+	MaxChatHistory = 100
+
+	// MaxChatMessageLength is the maximum length of a chat message in bytes.
+	MaxChatMessageLength = 280
+)
+
+var (
+	errMuted       = errors.New("muted")
+	errRateLimited = errors.New("too many messages")
+)
+
+// Reaction represents a reaction which can be sent in place of a chat message.
+type Reaction string
+
+// Possible reactions.
+const (
+	ReactionThumbsUp Reaction = "thumbs_up"
+	ReactionLaugh    Reaction = "laugh"
+	ReactionWow      Reaction = "wow"
+	ReactionSad      Reaction = "sad"
+	ReactionClap     Reaction = "clap"
+)
+
+var reactions = map[Reaction]bool{
+	ReactionThumbsUp: true,
+	ReactionLaugh:    true,
+	ReactionWow:      true,
+	ReactionSad:      true,
+	ReactionClap:     true,
+}
+
+// ChatMessage represents a chat message or reaction posted in a room.
+type ChatMessage struct {
+	// Player is the name of the player who posted a message.
+	Player string `json:"player"`
+
+	// Text is the content of a message. It is empty for reactions.
+	Text string `json:"text,omitempty"`
+
+	// Reaction is the reaction sent. It is empty for text messages.
+	Reaction Reaction `json:"reaction,omitempty"`
+
+	// Time is the time a message was posted.
+	Time int64 `json:"time"`
+}
+
+func (r *Room) muted(playerID string) bool {
+	for _, id := range r.Muted {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkChat returns an error if playerID cannot post message in the room.
+func (r *Room) checkChat(playerID string, message ChatMessage) error {
+	if r.seat(playerID) == -1 {
+		return errNotInRoom
+	}
+	if r.muted(playerID) {
+		return errMuted
+	}
+	text := strings.TrimSpace(message.Text)
+	if (text == "") == (message.Reaction == "") {
+		return errors.New("either text or reaction is required")
+	}
+	if len(text) > MaxChatMessageLength {
+		return errors.New("message too long")
+	}
+	if message.Reaction != "" && !reactions[message.Reaction] {
+		return errors.New("invalid reaction")
+	}
+	return nil
+}
+
+// postChat adds a chat message or reaction from playerID to the room.
+func (r *Room) postChat(playerID string, message ChatMessage, t time.Time) error {
+	if err := r.checkChat(playerID, message); err != nil {
+		return err
+	}
+	message.Text = strings.TrimSpace(message.Text)
+	message.Player = r.Players[r.seat(playerID)].Name
+	message.Time = t.UnixNano() / 1e6
+	r.Chat = append(r.Chat, message)
+	if len(r.Chat) > MaxChatHistory {
+		r.Chat = r.Chat[len(r.Chat)-MaxChatHistory:]
+	}
+	r.broadcast()
+	return nil
+}
+
+// setMuted mutes or unmutes the player in seat. Muted players cannot post in
+// the chat.
+func (r *Room) setMuted(playerID string, seat int, muted bool, t time.Time) error {
+	target, err := r.target(playerID, seat)
+	if err != nil {
+		return err
+	}
+	if r.muted(target.ID) == muted {
+		return nil
+	}
+	eventType := RoomEventUnmute
+	if muted {
+		eventType = RoomEventMute
+		r.Muted = append(r.Muted, target.ID)
+	} else {
+		for i, id := range r.Muted {
+			if id == target.ID {
+				r.Muted = append(r.Muted[:i], r.Muted[i+1:]...)
+				break
+			}
+		}
+	}
+	event := newRoomEvent(eventType, t)
+	event.Player = target.Name
+	r.Events = append(r.Events, event)
+	r.broadcast()
+	return nil
+}
+
+// clearChat removes all chat messages from the room.
+func (r *Room) clearChat(playerID string, t time.Time) error {
+	if err := r.checkHost(playerID); err != nil {
+		return err
+	}
+	r.Chat = []ChatMessage{}
+	r.Events = append(r.Events, newRoomEvent(RoomEventClearChat, t))
+	r.broadcast()
+	return nil
+}
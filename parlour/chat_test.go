@@ -0,0 +1,133 @@
+package parlour
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoom_postChat(t *testing.T) {
+	t.Run("not in room", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.postChat("id5", ChatMessage{Text: "hello"}, time.Now())
+		assert.EqualError(t, err, "not in room")
+	})
+	t.Run("muted", func(t *testing.T) {
+		r := newFullRoom()
+		r.Muted = []string{"id2"}
+		err := r.postChat("id2", ChatMessage{Text: "hello"}, time.Now())
+		assert.EqualError(t, err, "muted")
+	})
+	t.Run("invalid messages", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.postChat("id1", ChatMessage{Text: "  "}, time.Now())
+		assert.EqualError(t, err, "either text or reaction is required")
+		err = r.postChat("id1", ChatMessage{Text: "hello", Reaction: ReactionClap}, time.Now())
+		assert.EqualError(t, err, "either text or reaction is required")
+		err = r.postChat("id1", ChatMessage{Text: strings.Repeat("a", MaxChatMessageLength+1)}, time.Now())
+		assert.EqualError(t, err, "message too long")
+		err = r.postChat("id1", ChatMessage{Reaction: "shrug"}, time.Now())
+		assert.EqualError(t, err, "invalid reaction")
+		assert.Empty(t, r.Chat)
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.postChat("id2", ChatMessage{Player: "someone else", Text: " hello "}, time.Unix(1, 0))
+		assert.NoError(t, err)
+		err = r.postChat("id3", ChatMessage{Reaction: ReactionLaugh}, time.Unix(2, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, []ChatMessage{
+			{Player: "player2", Text: "hello", Time: 1000},
+			{Player: "player3", Reaction: ReactionLaugh, Time: 2000},
+		}, r.Chat)
+	})
+	t.Run("bounded history", func(t *testing.T) {
+		r := newFullRoom()
+		for i := 0; i <= MaxChatHistory; i++ {
+			_ = r.postChat("id1", ChatMessage{Text: "hello"}, time.Unix(int64(i), 0))
+		}
+		assert.Len(t, r.Chat, MaxChatHistory)
+		assert.Equal(t, int64(1000), r.Chat[0].Time)
+	})
+}
+
+func TestRoom_setMuted(t *testing.T) {
+	r := newFullRoom()
+	err := r.setMuted("id2", 2, true, time.Now())
+	assert.EqualError(t, err, "not host")
+
+	err = r.setMuted("id1", 1, true, time.Unix(1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id2"}, r.Muted)
+	assert.True(t, r.view("id1").Players[1].Muted)
+
+	err = r.setMuted("id1", 1, false, time.Unix(2, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, r.Muted)
+	assert.Equal(t, []RoomEvent{
+		{Type: RoomEventMute, Time: 1000, Player: "player2"},
+		{Type: RoomEventUnmute, Time: 2000, Player: "player2"},
+	}, r.Events)
+}
+
+func TestRoom_clearChat(t *testing.T) {
+	r := newFullRoom()
+	_ = r.postChat("id2", ChatMessage{Text: "hello"}, time.Now())
+	err := r.clearChat("id2", time.Now())
+	assert.EqualError(t, err, "not host")
+
+	err = r.clearChat("id1", time.Unix(1, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, r.Chat)
+	assert.Equal(t, []RoomEvent{{Type: RoomEventClearChat, Time: 1000}}, r.Events)
+}
+
+func Test_rateLimiter_allow(t *testing.T) {
+	l := newRateLimiter(2, time.Second)
+	start := time.Unix(0, 0)
+	assert.True(t, l.allow("alice", start))
+	assert.True(t, l.allow("alice", start.Add(100*time.Millisecond)))
+	assert.False(t, l.allow("alice", start.Add(200*time.Millisecond)))
+	assert.True(t, l.allow("bob", start.Add(200*time.Millisecond)))
+	assert.True(t, l.allow("alice", start.Add(time.Second)))
+	assert.False(t, l.allow("alice", start.Add(time.Second)))
+}
+
+func Test_rateLimiter_sweep(t *testing.T) {
+	l := newRateLimiter(2, time.Second)
+	start := time.Unix(0, 0)
+	l.allow("alice", start)
+	l.allow("bob", start.Add(500*time.Millisecond))
+	l.allow("carol", start.Add(900*time.Millisecond))
+	assert.Len(t, l.times, 3, "keys should only be swept once per window")
+	l.allow("carol", start.Add(2300*time.Millisecond))
+	assert.Equal(t, []string{"carol"}, keys(l.times))
+}
+
+func keys(m map[string][]time.Time) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func Test_roomService_PostChat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	roomRepository := NewMockRoomRepository(ctrl)
+	roomRepository.EXPECT().Save(gomock.Any()).Return(nil).AnyTimes()
+
+	service := newRoomService(roomRepository)
+	service.chatLimiter = newRateLimiter(1, time.Minute)
+	room := newFullRoom()
+	err := service.PostChat(room, "id1", ChatMessage{Text: " "})
+	assert.EqualError(t, err, "either text or reaction is required")
+	err = service.PostChat(room, "id1", ChatMessage{Text: "hello"})
+	assert.NoError(t, err, "rejected messages should not count towards the limit")
+	err = service.PostChat(room, "id1", ChatMessage{Text: "hello"})
+	assert.EqualError(t, err, "too many messages")
+}
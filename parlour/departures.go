@@ -0,0 +1,63 @@
+package parlour
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultGracePeriod is how long a vacated seat is left empty before a bot
+// takes it over.
+const DefaultGracePeriod = 30 * time.Second
+
+var errSeatVacated = errors.New("seat vacated")
+
+// vacateSeat marks the player in seat as having left during a game.
+func (r *Room) vacateSeat(seat int, t time.Time) {
+	r.Players[seat].Vacated = true
+	event := newRoomEvent(RoomEventVacate, t)
+	event.Player = r.Players[seat].Name
+	r.Events = append(r.Events, event)
+}
+
+// takeOverSeat has bot play in place of playerID if they have still not
+// returned to their seat. It returns whether the bot took over.
+func (r *Room) takeOverSeat(playerID string, bot string, t time.Time) bool {
+	for i, player := range r.Players {
+		if player.ID == playerID {
+			if !player.Vacated || player.Substitute != "" {
+				return false
+			}
+			r.Players[i].Substitute = bot
+			event := newRoomEvent(RoomEventTakeOver, t)
+			event.Player = player.Name
+			r.Events = append(r.Events, event)
+			r.broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// cancelTakeOver stops a bot from taking over the seat vacated by playerID.
+func (r *Room) cancelTakeOver(playerID string) {
+	if timer, ok := r.takeOvers[playerID]; ok {
+		timer.Stop()
+		delete(r.takeOvers, playerID)
+	}
+}
+
+// reclaimSeat returns a player to the seat they vacated, stopping any bot
+// playing in their place.
+func (r *Room) reclaimSeat(seat int, t time.Time) {
+	player := &r.Players[seat]
+	r.cancelTakeOver(player.ID)
+	if player.Substitute != "" {
+		r.stopBot(player.Substitute)
+	}
+	player.Vacated = false
+	player.Substitute = ""
+	event := newRoomEvent(RoomEventReclaim, t)
+	event.Player = player.Name
+	r.Events = append(r.Events, event)
+	r.broadcast()
+}
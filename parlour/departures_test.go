@@ -0,0 +1,110 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoom_removePlayer(t *testing.T) {
+	t.Run("removes player in lobby", func(t *testing.T) {
+		r := newFullRoom()
+		vacated := r.removePlayer("id2", time.Now())
+		assert.False(t, vacated)
+		assert.Len(t, r.Players, 3)
+	})
+	t.Run("vacates seat during game", func(t *testing.T) {
+		r := newFullRoom()
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		vacated := r.removePlayer("id2", time.Unix(1, 0))
+		assert.True(t, vacated)
+		assert.Equal(t, Player{ID: "id2", Name: "player2", Vacated: true}, r.Players[1])
+		assert.Equal(t, []RoomEvent{{Type: RoomEventVacate, Time: 1000, Player: "player2"}}, r.Events)
+
+		err := r.reduce("id2", Action{Nonce: r.Nonce, Type: ActionDraw})
+		assert.EqualError(t, err, "seat vacated")
+	})
+}
+
+func TestRoom_takeOverSeat(t *testing.T) {
+	r := newFullRoom()
+	_ = r.nextRound(SeatDrawNone, time.Now())
+	assert.False(t, r.takeOverSeat("id2", botNames[0], time.Now()))
+
+	r.removePlayer("id2", time.Now())
+	assert.True(t, r.takeOverSeat("id2", botNames[0], time.Unix(1, 0)))
+	assert.Equal(t, botNames[0], r.Players[1].Substitute)
+	assert.Equal(t, 1, r.seat(botNames[0]))
+	assert.Equal(t, RoomEvent{Type: RoomEventTakeOver, Time: 1000, Player: "player2"}, r.Events[1])
+
+	assert.False(t, r.takeOverSeat("id2", botNames[1], time.Now()))
+}
+
+func TestRoom_reclaimSeat(t *testing.T) {
+	r := newFullRoom()
+	r.Locked = true
+	_ = r.nextRound(SeatDrawNone, time.Now())
+	r.removePlayer("id2", time.Now())
+	r.takeOverSeat("id2", botNames[0], time.Now())
+	ch := make(chan RoomView, 1)
+	r.clients[ch] = botNames[0]
+
+	err := r.addPlayer(Player{ID: "id2", Name: "player2"})
+	assert.NoError(t, err)
+	assert.Equal(t, Player{ID: "id2", Name: "player2"}, r.Players[1])
+	assert.Equal(t, RoomEventReclaim, r.Events[2].Type)
+	assert.NotContains(t, r.clients, ch)
+	assert.Equal(t, -1, r.seat(botNames[0]))
+}
+
+func Test_roomService_RemovePlayer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	roomRepository := NewMockRoomRepository(ctrl)
+	roomRepository.EXPECT().Save(gomock.Any()).Return(nil).AnyTimes()
+
+	service := newRoomService(roomRepository)
+	service.GracePeriod = 0
+	room := newFullRoom()
+	_ = room.nextRound(SeatDrawNone, time.Now())
+	err := service.RemovePlayer(room, "id2")
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		room.RLock()
+		defer room.RUnlock()
+		return room.Players[1].Substitute != ""
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_roomService_scheduleTakeOver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	roomRepository := NewMockRoomRepository(ctrl)
+	roomRepository.EXPECT().Save(gomock.Any()).Return(nil).AnyTimes()
+
+	service := newRoomService(roomRepository)
+	service.GracePeriod = time.Hour
+	room := newFullRoom()
+	_ = room.nextRound(SeatDrawNone, time.Now())
+
+	_ = service.RemovePlayer(room, "id2")
+	first := room.takeOvers["id2"]
+	if !assert.NotNil(t, first) {
+		return
+	}
+
+	// returning cancels the take over
+	_ = room.addPlayer(Player{ID: "id2", Name: "player2"})
+	assert.Empty(t, room.takeOvers)
+	assert.False(t, first.Stop())
+
+	// leaving again starts a new grace period
+	_ = service.RemovePlayer(room, "id2")
+	second := room.takeOvers["id2"]
+	assert.NotNil(t, second)
+	assert.NotSame(t, first, second)
+	second.Stop()
+}
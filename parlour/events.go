@@ -0,0 +1,64 @@
+package parlour
+
+import (
+	"time"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// RoomEventType represents the type of a room event.
+type RoomEventType string
+
+// Possible room event types.
+const (
+	RoomEventSeatDraw       RoomEventType = "seat_draw"
+	RoomEventKick           RoomEventType = "kick"
+	RoomEventTransferHost   RoomEventType = "transfer_host"
+	RoomEventLock           RoomEventType = "lock"
+	RoomEventUnlock         RoomEventType = "unlock"
+	RoomEventReplaceWithBot RoomEventType = "replace_with_bot"
+	RoomEventVacate         RoomEventType = "vacate"
+	RoomEventTakeOver       RoomEventType = "take_over"
+	RoomEventReclaim        RoomEventType = "reclaim"
+	RoomEventMute           RoomEventType = "mute"
+	RoomEventUnmute         RoomEventType = "unmute"
+	RoomEventClearChat      RoomEventType = "clear_chat"
+	RoomEventAdjustScores   RoomEventType = "adjust_scores"
+	RoomEventVoidRound      RoomEventType = "void_round"
+)
+
+// RoomEvent represents something which happened in a room outside of a round.
+type RoomEvent struct {
+	// Type is the type of an event.
+	Type RoomEventType `json:"type"`
+
+	// Time is the time an event occurred.
+	Time int64 `json:"time"`
+
+	// Player is the name of the player an event pertains to.
+	Player string `json:"player,omitempty"`
+
+	// SeatDraw is the method used to assign seats in a seat draw.
+	SeatDraw SeatDraw `json:"seat_draw,omitempty"`
+
+	// Seats contains the new seat of each player in a seat draw, indexed by
+	// their previous seat.
+	Seats []int `json:"seats,omitempty"`
+
+	// Tiles contains the tiles drawn by each player in a seat draw, indexed by
+	// their previous seat.
+	Tiles []mahjong.Tile `json:"tiles,omitempty"`
+
+	// Reason is the reason the host changed the players' scores.
+	Reason LedgerReason `json:"reason,omitempty"`
+
+	// Deltas contains how much each player's score was changed by the host.
+	Deltas []int `json:"deltas,omitempty"`
+}
+
+func newRoomEvent(eventType RoomEventType, t time.Time) RoomEvent {
+	return RoomEvent{
+		Type: eventType,
+		Time: t.UnixNano() / 1e6,
+	}
+}
@@ -0,0 +1,129 @@
+package parlour
+
+import (
+	"errors"
+	"time"
+)
+
+// Default and maximum number of rooms in a page of a player's room history.
+const (
+	DefaultRoomPageSize = 20
+	MaxRoomPageSize     = 100
+)
+
+var phaseNames = map[string]Phase{
+	"lobby":       PhaseLobby,
+	"in_progress": PhaseInProgress,
+	"finished":    PhaseFinished,
+}
+
+// parsePhase returns the phase with a name used in query parameters.
+func parsePhase(name string) (Phase, error) {
+	phase, ok := phaseNames[name]
+	if !ok {
+		return 0, errors.New("invalid phase")
+	}
+	return phase, nil
+}
+
+// PlayerRoomFilter selects which of the rooms a player has been in to list.
+type PlayerRoomFilter struct {
+	// Phases contains the phases rooms must be in. Rooms in any phase are
+	// listed if it is empty.
+	Phases []Phase
+
+	Limit  int
+	Offset int
+}
+
+func (f PlayerRoomFilter) matches(phase Phase) bool {
+	if len(f.Phases) == 0 {
+		return true
+	}
+	for _, p := range f.Phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomSummary describes a room a player has been in.
+type RoomSummary struct {
+	ID      string   `json:"id"`
+	Phase   Phase    `json:"phase"`
+	Host    string   `json:"host"`
+	Players []string `json:"players"`
+	Ruleset Ruleset  `json:"ruleset"`
+	Scores  [4]int   `json:"scores"`
+
+	// JoinedAt is when the player first joined the room in milliseconds
+	// since the Unix epoch.
+	JoinedAt int64 `json:"joined_at"`
+}
+
+// RoomPage is a page of the rooms a player has been in, most recently joined
+// first.
+type RoomPage struct {
+	Rooms []RoomSummary `json:"rooms"`
+
+	// NextOffset is the offset of the next page, or zero if this is the last
+	// page.
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// summary returns a summary of a room for a player who joined it at
+// joinedAt.
+func (r *Room) summary(joinedAt time.Time) RoomSummary {
+	summary := RoomSummary{
+		ID:       r.ID,
+		Phase:    r.Phase,
+		Players:  make([]string, len(r.Players)),
+		Ruleset:  r.ruleset(),
+		Scores:   r.Scores,
+		JoinedAt: joinedAt.UnixNano() / int64(time.Millisecond),
+	}
+	for i, player := range r.Players {
+		summary.Players[i] = player.Name
+	}
+	if seat := r.seat(r.Host); seat != -1 {
+		summary.Host = r.Players[seat].Name
+	}
+	return summary
+}
+
+// members returns the IDs of the human players seated in a room.
+func (r *Room) members() []string {
+	var ids []string
+	for _, player := range r.Players {
+		if !player.IsBot {
+			ids = append(ids, player.ID)
+		}
+	}
+	return ids
+}
+
+// ListPlayerRooms returns a page of the rooms a player has been in.
+func (s *roomService) ListPlayerRooms(playerID string, filter PlayerRoomFilter) (RoomPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DefaultRoomPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > MaxRoomPageSize {
+		return RoomPage{}, &Error{error: errors.New("invalid limit")}
+	}
+	if filter.Offset < 0 {
+		return RoomPage{}, &Error{error: errors.New("invalid offset")}
+	}
+	limit := filter.Limit
+	filter.Limit++
+	rooms, err := s.RoomRepository.ListByPlayer(playerID, filter)
+	if err != nil {
+		return RoomPage{}, &Error{error: err, internal: true}
+	}
+	page := RoomPage{Rooms: rooms}
+	if len(rooms) > limit {
+		page.Rooms = rooms[:limit]
+		page.NextOffset = filter.Offset + limit
+	}
+	return page, nil
+}
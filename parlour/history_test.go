@@ -0,0 +1,116 @@
+package parlour
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryRoomRepository_ListByPlayer(t *testing.T) {
+	repo := NewInMemoryRoomRepository()
+	first := NewRoom(Player{ID: "alice", Name: "Alice"})
+	first.Phase = PhaseFinished
+	second := NewRoom(Player{ID: "bob", Name: "Bob"})
+	_ = second.addPlayer(Player{ID: "alice", Name: "Alice"})
+	_ = second.addPlayer(Player{ID: "bot1", Name: "Bot", IsBot: true})
+	_ = repo.Save(first)
+	_ = repo.Save(second)
+
+	t.Run("most recently joined first", func(t *testing.T) {
+		rooms, err := repo.ListByPlayer("alice", PlayerRoomFilter{Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, rooms, 2) {
+			assert.Equal(t, second.ID, rooms[0].ID)
+			assert.Equal(t, "Bob", rooms[0].Host)
+			assert.Equal(t, []string{"Bob", "Alice", "Bot"}, rooms[0].Players)
+			assert.Equal(t, first.ID, rooms[1].ID)
+		}
+	})
+	t.Run("filters by phase", func(t *testing.T) {
+		rooms, err := repo.ListByPlayer("alice", PlayerRoomFilter{Phases: []Phase{PhaseFinished}, Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, rooms, 1) {
+			assert.Equal(t, first.ID, rooms[0].ID)
+		}
+	})
+	t.Run("paginates", func(t *testing.T) {
+		rooms, err := repo.ListByPlayer("alice", PlayerRoomFilter{Limit: 1, Offset: 1})
+		assert.NoError(t, err)
+		if assert.Len(t, rooms, 1) {
+			assert.Equal(t, first.ID, rooms[0].ID)
+		}
+		rooms, err = repo.ListByPlayer("alice", PlayerRoomFilter{Limit: 1, Offset: 2})
+		assert.NoError(t, err)
+		assert.Empty(t, rooms)
+	})
+	t.Run("keeps rooms players have left", func(t *testing.T) {
+		second.removePlayer("alice", time.Now())
+		_ = repo.Save(second)
+		rooms, err := repo.ListByPlayer("alice", PlayerRoomFilter{Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, rooms, 2)
+	})
+	t.Run("does not index bots", func(t *testing.T) {
+		rooms, err := repo.ListByPlayer("bot1", PlayerRoomFilter{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, rooms)
+	})
+}
+
+func Test_roomService_ListPlayerRooms(t *testing.T) {
+	s := newRoomService(NewInMemoryRoomRepository())
+	for i := 0; i < 3; i++ {
+		_, _ = s.Create(Player{ID: "alice", Name: "Alice"})
+	}
+
+	page, err := s.ListPlayerRooms("alice", PlayerRoomFilter{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Rooms, 2)
+	assert.Equal(t, 2, page.NextOffset)
+
+	page, err = s.ListPlayerRooms("alice", PlayerRoomFilter{Limit: 2, Offset: page.NextOffset})
+	assert.NoError(t, err)
+	assert.Len(t, page.Rooms, 1)
+	assert.Zero(t, page.NextOffset)
+
+	_, err = s.ListPlayerRooms("alice", PlayerRoomFilter{Limit: MaxRoomPageSize + 1})
+	assert.EqualError(t, err, "invalid limit")
+}
+
+func TestParlour_myRoomsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+	_, token, _ := parlour.tokenService.Create("alice", "")
+	room, _ := parlour.roomService.Create(Player{ID: "alice", Name: "Alice"})
+
+	do := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/me/rooms"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("?phase=lobby")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page RoomPage
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if assert.Len(t, page.Rooms, 1) {
+		assert.Equal(t, room.ID, page.Rooms[0].ID)
+	}
+
+	w = do("?phase=finished")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"rooms": []}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, do("?phase=over").Code)
+	assert.Equal(t, http.StatusBadRequest, do("?offset=-1").Code)
+}
@@ -0,0 +1,170 @@
+package parlour
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	errNotHost     = errors.New("not host")
+	errRoomLocked  = errors.New("room locked")
+	errInvalidSeat = errors.New("invalid seat")
+
+	// errTournamentRoom is returned for host controls which would let the
+	// host change the outcome of a tournament table.
+	errTournamentRoom = errors.New("not allowed in a tournament")
+)
+
+// checkHost returns an error unless playerID belongs to the host of the room.
+func (r *Room) checkHost(playerID string) error {
+	if r.seat(playerID) == -1 {
+		return errNotInRoom
+	}
+	if r.Host != playerID {
+		return errNotHost
+	}
+	return nil
+}
+
+// target returns the player in seat who the host wants to act on.
+func (r *Room) target(playerID string, seat int) (Player, error) {
+	if err := r.checkHost(playerID); err != nil {
+		return Player{}, err
+	}
+	if seat < 0 || seat >= len(r.Players) {
+		return Player{}, errInvalidSeat
+	}
+	target := r.Players[seat]
+	if target.ID == playerID {
+		return Player{}, errors.New("cannot target yourself")
+	}
+	return target, nil
+}
+
+// kickPlayer removes the player in seat from the room. Players cannot be
+// kicked while a game is in progress, but they can be replaced by a bot.
+func (r *Room) kickPlayer(playerID string, seat int, t time.Time) error {
+	if r.Tournament != "" {
+		return errTournamentRoom
+	}
+	target, err := r.target(playerID, seat)
+	if err != nil {
+		return err
+	}
+	if r.Phase == PhaseInProgress {
+		return errors.New("game in progress")
+	}
+	r.Players = append(r.Players[:seat], r.Players[seat+1:]...)
+	if target.IsBot {
+		r.stopBot(target.ID)
+	} else if target.Substitute != "" {
+		r.stopBot(target.Substitute)
+	}
+	event := newRoomEvent(RoomEventKick, t)
+	event.Player = target.Name
+	r.Events = append(r.Events, event)
+	r.broadcast()
+	return nil
+}
+
+// transferHost makes the player in seat the new host of the room.
+func (r *Room) transferHost(playerID string, seat int, t time.Time) error {
+	target, err := r.target(playerID, seat)
+	if err != nil {
+		return err
+	}
+	if target.IsBot {
+		return errors.New("cannot transfer host to a bot")
+	}
+	r.Host = target.ID
+	event := newRoomEvent(RoomEventTransferHost, t)
+	event.Player = target.Name
+	r.Events = append(r.Events, event)
+	r.broadcast()
+	return nil
+}
+
+// setLocked locks or unlocks the room. New players cannot join a locked room.
+func (r *Room) setLocked(playerID string, locked bool, t time.Time) error {
+	if r.Tournament != "" {
+		return errTournamentRoom
+	}
+	if err := r.checkHost(playerID); err != nil {
+		return err
+	}
+	if r.Locked == locked {
+		return nil
+	}
+	r.Locked = locked
+	eventType := RoomEventUnlock
+	if locked {
+		eventType = RoomEventLock
+	}
+	r.Events = append(r.Events, newRoomEvent(eventType, t))
+	r.broadcast()
+	return nil
+}
+
+// replaceWithBot gives the seat of the player in seat to bot. If the player
+// had left, any bot waiting to take over or playing in their place is stopped.
+func (r *Room) replaceWithBot(playerID string, seat int, bot Player, t time.Time) error {
+	if r.Tournament != "" {
+		return errTournamentRoom
+	}
+	target, err := r.target(playerID, seat)
+	if err != nil {
+		return err
+	}
+	if target.IsBot {
+		return errors.New("already a bot")
+	}
+	r.cancelTakeOver(target.ID)
+	if target.Substitute != "" {
+		r.stopBot(target.Substitute)
+	}
+	r.Players[seat] = bot
+	event := newRoomEvent(RoomEventReplaceWithBot, t)
+	event.Player = target.Name
+	r.Events = append(r.Events, event)
+	r.broadcast()
+	return nil
+}
+
+// voidRound cancels the round in progress, undoing every payment made during
+// it. The next round is started as it would be after a draw.
+func (r *Room) voidRound(playerID string, t time.Time) error {
+	if r.Tournament != "" {
+		return errTournamentRoom
+	}
+	if err := r.checkHost(playerID); err != nil {
+		return err
+	}
+	if r.Phase != PhaseInProgress || r.Round.Finished {
+		return errors.New("round not in progress")
+	}
+	r.Round.Void(t)
+	r.recordAction(-1, t, Action{Type: ActionVoidRound})
+	r.Events = append(r.Events, newRoomEvent(RoomEventVoidRound, t))
+	r.broadcast()
+	return nil
+}
+
+// nextBotName returns the name of a bot which is not already in the room.
+func (r *Room) nextBotName() string {
+	for _, name := range botNames {
+		if r.seat(name) == -1 {
+			return name
+		}
+	}
+	return ""
+}
+
+// stopBot unsubscribes a bot from the room, which stops it from making moves.
+func (r *Room) stopBot(id string) {
+	for ch, clientID := range r.clients {
+		if clientID == id {
+			delete(r.clients, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+func TestRoom_kickPlayer(t *testing.T) {
+	t.Run("not host", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.kickPlayer("id2", 2, time.Now())
+		assert.EqualError(t, err, "not host")
+	})
+	t.Run("invalid seat", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.kickPlayer("id1", 4, time.Now())
+		assert.EqualError(t, err, "invalid seat")
+	})
+	t.Run("cannot kick yourself", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.kickPlayer("id1", 0, time.Now())
+		assert.EqualError(t, err, "cannot target yourself")
+	})
+	t.Run("game in progress", func(t *testing.T) {
+		r := newFullRoom()
+		r.Phase = PhaseInProgress
+		err := r.kickPlayer("id1", 1, time.Now())
+		assert.EqualError(t, err, "game in progress")
+	})
+	t.Run("tournament", func(t *testing.T) {
+		r := newFullRoom()
+		r.Tournament = "T1"
+		err := r.kickPlayer("id1", 1, time.Now())
+		assert.Equal(t, errTournamentRoom, err)
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.kickPlayer("id1", 1, time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, []Player{
+			{ID: "id1", Name: "player1"},
+			{ID: "id3", Name: "player3"},
+			{ID: "id4", Name: "player4"},
+		}, r.Players)
+		assert.Equal(t, []RoomEvent{{Type: RoomEventKick, Time: 1000, Player: "player2"}}, r.Events)
+	})
+	t.Run("stops kicked bot", func(t *testing.T) {
+		r := newFullRoom()
+		r.Players[1] = Player{ID: botNames[0], Name: botNames[0], IsBot: true}
+		ch := make(chan RoomView, 1)
+		r.clients[ch] = botNames[0]
+		err := r.kickPlayer("id1", 1, time.Now())
+		assert.NoError(t, err)
+		assert.NotContains(t, r.clients, ch)
+		_, ok := <-ch
+		assert.False(t, ok)
+	})
+}
+
+func TestRoom_transferHost(t *testing.T) {
+	t.Run("cannot transfer to bot", func(t *testing.T) {
+		r := newFullRoom()
+		r.Players[1].IsBot = true
+		err := r.transferHost("id1", 1, time.Now())
+		assert.EqualError(t, err, "cannot transfer host to a bot")
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.transferHost("id1", 2, time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, "id3", r.Host)
+		assert.Equal(t, []RoomEvent{{Type: RoomEventTransferHost, Time: 1000, Player: "player3"}}, r.Events)
+
+		err = r.transferHost("id1", 1, time.Now())
+		assert.EqualError(t, err, "not host")
+	})
+}
+
+func TestRoom_setLocked(t *testing.T) {
+	r := NewRoom(Player{ID: "id1", Name: "player1"})
+	err := r.setLocked("id1", true, time.Unix(1, 0))
+	assert.NoError(t, err)
+	assert.True(t, r.Locked)
+
+	err = r.addPlayer(Player{ID: "id2", Name: "player2"})
+	assert.EqualError(t, err, "room locked")
+
+	err = r.setLocked("id1", false, time.Unix(2, 0))
+	assert.NoError(t, err)
+	assert.False(t, r.Locked)
+	assert.Equal(t, []RoomEvent{
+		{Type: RoomEventLock, Time: 1000},
+		{Type: RoomEventUnlock, Time: 2000},
+	}, r.Events)
+
+	err = r.addPlayer(Player{ID: "id2", Name: "player2"})
+	assert.NoError(t, err)
+
+	r.Tournament = "T1"
+	assert.Equal(t, errTournamentRoom, r.setLocked("id1", true, time.Now()))
+}
+
+func TestRoom_replaceWithBot(t *testing.T) {
+	bot := Player{ID: botNames[0], Name: botNames[0], IsBot: true}
+	t.Run("already a bot", func(t *testing.T) {
+		r := newFullRoom()
+		r.Players[1] = bot
+		err := r.replaceWithBot("id1", 1, Player{ID: botNames[1], Name: botNames[1], IsBot: true}, time.Now())
+		assert.EqualError(t, err, "already a bot")
+	})
+	t.Run("tournament", func(t *testing.T) {
+		r := newFullRoom()
+		r.Tournament = "T1"
+		err := r.replaceWithBot("id1", 1, bot, time.Now())
+		assert.Equal(t, errTournamentRoom, err)
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newFullRoom()
+		r.Phase = PhaseInProgress
+		err := r.replaceWithBot("id1", 1, bot, time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, bot, r.Players[1])
+		assert.Equal(t, []RoomEvent{{Type: RoomEventReplaceWithBot, Time: 1000, Player: "player2"}}, r.Events)
+		assert.Equal(t, botNames[1], r.nextBotName())
+	})
+	t.Run("stops substitute", func(t *testing.T) {
+		r := newFullRoom()
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		r.removePlayer("id2", time.Now())
+		r.takeOverSeat("id2", botNames[0], time.Now())
+		ch := make(chan RoomView, 1)
+		r.clients[ch] = botNames[0]
+		replacement := Player{ID: botNames[1], Name: botNames[1], IsBot: true}
+		err := r.replaceWithBot("id1", 1, replacement, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, replacement, r.Players[1])
+		assert.NotContains(t, r.clients, ch)
+		_, ok := <-ch
+		assert.False(t, ok)
+	})
+	t.Run("cancels take over", func(t *testing.T) {
+		r := newFullRoom()
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		r.removePlayer("id2", time.Now())
+		timer := time.AfterFunc(time.Hour, func() {})
+		r.takeOvers = map[string]*time.Timer{"id2": timer}
+		err := r.replaceWithBot("id1", 1, bot, time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, r.takeOvers)
+		assert.False(t, timer.Stop())
+	})
+}
+
+func TestRoom_voidRound(t *testing.T) {
+	newStartedRoom := func() *Room {
+		r := newFullRoom()
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		return r
+	}
+	t.Run("not host", func(t *testing.T) {
+		r := newStartedRoom()
+		assert.EqualError(t, r.voidRound("id2", time.Now()), "not host")
+	})
+	t.Run("tournament", func(t *testing.T) {
+		r := newStartedRoom()
+		r.Tournament = "T1"
+		assert.Equal(t, errTournamentRoom, r.voidRound("id1", time.Now()))
+	})
+	t.Run("round not in progress", func(t *testing.T) {
+		r := newFullRoom()
+		assert.EqualError(t, r.voidRound("id1", time.Now()), "round not in progress")
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newStartedRoom()
+		r.Round.ScoreEvents = []mahjong.ScoreEvent{{Reason: mahjong.ScoreExposedGang, Payer: 1, Payee: 0, Amount: 2}}
+		r.Round.Scores = [4]int{2, -2, 0, 0}
+		err := r.voidRound("id1", time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, [4]int{}, r.Round.Scores)
+		assert.True(t, r.Round.Result.Voided)
+		assert.Equal(t, RoomEventVoidRound, r.Events[len(r.Events)-1].Type)
+		assert.EqualError(t, r.voidRound("id1", time.Now()), "round not in progress")
+
+		assert.NoError(t, r.nextRound(SeatDrawNone, time.Now()))
+		assert.True(t, r.Results[0].Voided)
+		assert.Empty(t, r.Ledger)
+		assert.Equal(t, [4]int{}, r.Scores)
+	})
+}
+
+func TestRoom_removePlayer_host(t *testing.T) {
+	r := newFullRoom()
+	r.Players[1].IsBot = true
+	r.removePlayer("id1", time.Now())
+	assert.Equal(t, "id3", r.Host)
+}
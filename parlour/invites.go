@@ -0,0 +1,136 @@
+package parlour
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultInviteDuration is how long an invite is valid for if no
+	// duration is given.
+	DefaultInviteDuration = 24 * time.Hour
+
+	// MaxInviteDuration is the longest an invite can be valid for.
+	MaxInviteDuration = 7 * 24 * time.Hour
+)
+
+// Attempts to join rooms with a passcode are limited to PlayerJoinAttempts
+// for each player and RoomJoinAttempts for each room within JoinWindow, so
+// that passcodes cannot be guessed.
+const (
+	PlayerJoinAttempts = 5
+	RoomJoinAttempts   = 20
+	JoinWindow         = time.Minute
+)
+
+var (
+	errPasscodeRequired  = errors.New("passcode required")
+	errIncorrectPasscode = errors.New("incorrect passcode")
+	errInvalidInvite     = errors.New("invalid invite")
+	errInviteExpired     = errors.New("invite expired")
+	errTooManyJoins      = errors.New("too many join attempts")
+)
+
+// JoinCredentials are presented by a player joining a room which has a
+// passcode. Either the passcode or a valid invite is enough to join.
+type JoinCredentials struct {
+	Passcode string
+	Invite   string
+}
+
+// inviter issues and verifies signed invites to rooms.
+type inviter struct {
+	key []byte
+}
+
+// newInviteKey returns a random key for signing invites.
+func newInviteKey() []byte {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func (i inviter) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// issue returns an invite to roomID which expires at expiry.
+func (i inviter) issue(roomID string, expiry time.Time) string {
+	payload := roomID + ":" + strconv.FormatInt(expiry.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(i.sign(payload))
+}
+
+// verify returns an error unless invite is a valid invite to roomID at t.
+func (i inviter) verify(invite, roomID string, t time.Time) error {
+	parts := strings.Split(invite, ".")
+	if len(parts) != 2 {
+		return errInvalidInvite
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return errInvalidInvite
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return errInvalidInvite
+	}
+	if !hmac.Equal(signature, i.sign(string(payload))) {
+		return errInvalidInvite
+	}
+	fields := strings.Split(string(payload), ":")
+	if len(fields) != 2 || fields[0] != roomID {
+		return errInvalidInvite
+	}
+	expiry, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return errInvalidInvite
+	}
+	if t.Unix() >= expiry {
+		return errInviteExpired
+	}
+	return nil
+}
+
+// checkPasscode returns an error unless credentials allow a player to join a
+// room with a passcode.
+func (r *Room) checkPasscode(inviter inviter, credentials JoinCredentials, t time.Time) error {
+	if r.Passcode == "" {
+		return nil
+	}
+	if credentials.Invite != "" {
+		return inviter.verify(credentials.Invite, r.ID, t)
+	}
+	if credentials.Passcode == "" {
+		return errPasscodeRequired
+	}
+	if subtle.ConstantTimeCompare([]byte(credentials.Passcode), []byte(r.Passcode)) != 1 {
+		return errIncorrectPasscode
+	}
+	return nil
+}
+
+// invite returns an invite to the room which is valid for duration.
+func (r *Room) invite(inviter inviter, playerID string, duration time.Duration, t time.Time) (string, error) {
+	if r.seat(playerID) == -1 {
+		return "", errNotInRoom
+	}
+	if duration == 0 {
+		duration = DefaultInviteDuration
+	}
+	if duration < 0 || duration > MaxInviteDuration {
+		return "", errors.New("invalid invite duration")
+	}
+	return inviter.issue(r.ID, t.Add(duration)), nil
+}
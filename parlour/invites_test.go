@@ -0,0 +1,101 @@
+package parlour
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_inviter(t *testing.T) {
+	i := inviter{key: []byte("secret")}
+	now := time.Unix(1000, 0)
+	invite := i.issue("ABCD", now.Add(time.Hour))
+
+	assert.NoError(t, i.verify(invite, "ABCD", now))
+	assert.Equal(t, errInvalidInvite, i.verify(invite, "WXYZ", now))
+	assert.Equal(t, errInviteExpired, i.verify(invite, "ABCD", now.Add(time.Hour)))
+	assert.Equal(t, errInvalidInvite, i.verify("garbage", "ABCD", now))
+	assert.Equal(t, errInvalidInvite, inviter{key: []byte("other")}.verify(invite, "ABCD", now))
+
+	forged := i.issue("ABCD", now.Add(2*time.Hour))
+	assert.Equal(t, errInvalidInvite, i.verify(forged[:len(forged)-43]+invite[len(invite)-43:], "ABCD", now))
+}
+
+func TestRoom_checkPasscode(t *testing.T) {
+	i := inviter{key: []byte("secret")}
+	now := time.Unix(1000, 0)
+	r := NewRoom(Player{ID: "id1", Name: "player1"})
+	r.ID = "ABCD"
+	assert.NoError(t, r.checkPasscode(i, JoinCredentials{}, now))
+
+	r.Passcode = "hunter2"
+	assert.Equal(t, errPasscodeRequired, r.checkPasscode(i, JoinCredentials{}, now))
+	assert.Equal(t, errIncorrectPasscode, r.checkPasscode(i, JoinCredentials{Passcode: "hunter3"}, now))
+	assert.NoError(t, r.checkPasscode(i, JoinCredentials{Passcode: "hunter2"}, now))
+
+	invite, err := r.invite(i, "id1", time.Hour, now)
+	assert.NoError(t, err)
+	assert.NoError(t, r.checkPasscode(i, JoinCredentials{Invite: invite}, now))
+	assert.Equal(t, errInviteExpired, r.checkPasscode(i, JoinCredentials{Invite: invite}, now.Add(time.Hour)))
+}
+
+func TestRoom_invite(t *testing.T) {
+	i := inviter{key: []byte("secret")}
+	r := NewRoom(Player{ID: "id1", Name: "player1"})
+	_, err := r.invite(i, "id2", 0, time.Now())
+	assert.Equal(t, errNotInRoom, err)
+	_, err = r.invite(i, "id1", MaxInviteDuration+time.Second, time.Now())
+	assert.EqualError(t, err, "invalid invite duration")
+}
+
+func Test_roomService_AddPlayer_passcode(t *testing.T) {
+	service := newRoomService(NewInMemoryRoomRepository())
+	room, _ := service.Create(Player{ID: "id1", Name: "player1"})
+	room.Passcode = "hunter2"
+
+	err := service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{})
+	assert.EqualError(t, err, "passcode required")
+
+	invite, _ := service.Invite(room, "id1", 0)
+	err = service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{Invite: invite})
+	assert.NoError(t, err)
+
+	err = service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{})
+	assert.NoError(t, err, "players already in the room do not need a passcode")
+}
+
+func Test_roomService_AddPlayer_rateLimited(t *testing.T) {
+	t.Run("per player", func(t *testing.T) {
+		service := newRoomService(NewInMemoryRoomRepository())
+		room, _ := service.Create(Player{ID: "id1", Name: "player1"})
+		room.Passcode = "hunter2"
+		for i := 0; i < PlayerJoinAttempts; i++ {
+			err := service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{Passcode: "guess"})
+			assert.EqualError(t, err, "incorrect passcode")
+		}
+		err := service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{Passcode: "hunter2"})
+		assert.Equal(t, errTooManyJoins, errors.Unwrap(err))
+	})
+	t.Run("per room", func(t *testing.T) {
+		service := newRoomService(NewInMemoryRoomRepository())
+		room, _ := service.Create(Player{ID: "id1", Name: "player1"})
+		room.Passcode = "hunter2"
+		for i := 0; i < RoomJoinAttempts; i++ {
+			player := Player{ID: fmt.Sprintf("guest%d", i), Name: "guest"}
+			err := service.AddPlayer(room, player, JoinCredentials{Passcode: "guess"})
+			assert.EqualError(t, err, "incorrect passcode")
+		}
+		err := service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{Passcode: "hunter2"})
+		assert.Equal(t, errTooManyJoins, errors.Unwrap(err))
+	})
+	t.Run("rooms without a passcode", func(t *testing.T) {
+		service := newRoomService(NewInMemoryRoomRepository())
+		room, _ := service.Create(Player{ID: "id1", Name: "player1"})
+		assert.NoError(t, service.AddPlayer(room, Player{ID: "id2", Name: "player2"}, JoinCredentials{}))
+		assert.Empty(t, service.playerJoinLimiter.times)
+		assert.Empty(t, service.roomJoinLimiter.times)
+	})
+}
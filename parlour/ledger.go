@@ -0,0 +1,185 @@
+package parlour
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// LedgerReason represents the reason for a change in scores.
+type LedgerReason string
+
+// Possible ledger reasons.
+const (
+	LedgerWin        LedgerReason = "win"
+	LedgerBite       LedgerReason = "bite"
+	LedgerGang       LedgerReason = "gang"
+	LedgerFlowerSet  LedgerReason = "flower_set"
+	LedgerPenalty    LedgerReason = "penalty"
+	LedgerAdjustment LedgerReason = "adjustment"
+)
+
+// Adjustment is a change in scores made by the host of a room outside of
+// play, such as a penalty for a false win or a correction.
+type Adjustment struct {
+	// Reason is either LedgerPenalty or LedgerAdjustment.
+	Reason LedgerReason `json:"reason"`
+
+	// Deltas contains how much each player's score changes. They must add
+	// up to zero.
+	Deltas [4]int `json:"deltas"`
+}
+
+func (a Adjustment) validate() error {
+	sum, payers := 0, 0
+	for _, delta := range a.Deltas {
+		sum += delta
+		if delta < 0 {
+			payers++
+		}
+	}
+	switch a.Reason {
+	case LedgerPenalty:
+		if payers != 1 {
+			return errors.New("penalty must be paid by one player")
+		}
+	case LedgerAdjustment:
+		if payers == 0 {
+			return errors.New("adjustment has no changes")
+		}
+	default:
+		return errors.New("invalid reason")
+	}
+	if sum != 0 {
+		return errors.New("deltas must add up to zero")
+	}
+	return nil
+}
+
+// LedgerEntry records a change in the players' scores.
+type LedgerEntry struct {
+	// Round is the index of the round in which a change happened.
+	Round int `json:"round"`
+
+	// Reason is the reason for a change.
+	Reason LedgerReason `json:"reason"`
+
+	// Deltas contains how much each player's score changed.
+	Deltas [4]int `json:"deltas"`
+
+	// Time is when a change happened, in milliseconds since the epoch.
+	Time int64 `json:"time,omitempty"`
+}
+
+func ledgerReason(reason mahjong.ScoreReason) LedgerReason {
+	switch reason {
+	case mahjong.ScoreBitten:
+		return LedgerBite
+	case mahjong.ScoreExposedGang, mahjong.ScoreConcealedGang:
+		return LedgerGang
+	case mahjong.ScoreFlowerSet:
+		return LedgerFlowerSet
+	}
+	return LedgerAdjustment
+}
+
+// ledgerEntries returns the ledger entries for a finished round. Payments
+// made to the same player for the same reason at the same time are combined
+// into a single entry.
+func ledgerEntries(index int, round *mahjong.Round) []LedgerEntry {
+	var entries []LedgerEntry
+	var last *mahjong.ScoreEvent
+	for i, event := range round.ScoreEvents {
+		if last == nil || event.Reason != last.Reason || event.Payee != last.Payee || event.Time != last.Time {
+			entries = append(entries, LedgerEntry{
+				Round:  index,
+				Reason: ledgerReason(event.Reason),
+				Time:   event.Time,
+			})
+		}
+		entry := &entries[len(entries)-1]
+		entry.Deltas[event.Payer] -= event.Amount
+		entry.Deltas[event.Payee] += event.Amount
+		last = &round.ScoreEvents[i]
+	}
+	if round.Result != nil && round.Result.Winner != -1 {
+		entry := LedgerEntry{
+			Round:  index,
+			Reason: LedgerWin,
+			Deltas: round.Result.Payments,
+		}
+		if !round.LastActionTime.IsZero() {
+			entry.Time = millis(round.LastActionTime)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// sortLedger puts ledger entries in the order they happened. A round's
+// payments are only added to the ledger once it is over, after any
+// adjustments made during it.
+func sortLedger(ledger []LedgerEntry) {
+	sort.SliceStable(ledger, func(i, j int) bool {
+		if ledger[i].Round != ledger[j].Round {
+			return ledger[i].Round < ledger[j].Round
+		}
+		return ledger[i].Time < ledger[j].Time
+	})
+}
+
+// adjustScores applies a penalty or adjustment made by the host to the
+// players' scores and records it in the ledger. Scores can only be adjusted
+// once a game has started, and not after a rated game has finished because
+// ratings are not recalculated.
+func (r *Room) adjustScores(playerID string, adjustment Adjustment, t time.Time) error {
+	if r.Tournament != "" {
+		return errTournamentRoom
+	}
+	if err := r.checkHost(playerID); err != nil {
+		return err
+	}
+	if err := adjustment.validate(); err != nil {
+		return err
+	}
+	round := len(r.Results)
+	switch r.Phase {
+	case PhaseInProgress:
+		// the round's scores carry over to the next round, so they have to
+		// be adjusted too
+		for i, delta := range adjustment.Deltas {
+			r.Round.Scores[i] += delta
+		}
+	case PhaseFinished:
+		if r.rated() {
+			return errors.New("game already rated")
+		}
+		round--
+	default:
+		return errors.New("game not started")
+	}
+	for i, delta := range adjustment.Deltas {
+		r.Scores[i] += delta
+	}
+	r.Ledger = append(r.Ledger, LedgerEntry{
+		Round:  round,
+		Reason: adjustment.Reason,
+		Deltas: adjustment.Deltas,
+		Time:   millis(t),
+	})
+	event := newRoomEvent(RoomEventAdjustScores, t)
+	event.Reason = adjustment.Reason
+	event.Deltas = adjustment.Deltas[:]
+	if adjustment.Reason == LedgerPenalty {
+		for seat, delta := range adjustment.Deltas {
+			if delta < 0 {
+				event.Player = r.Players[seat].Name
+			}
+		}
+	}
+	r.Events = append(r.Events, event)
+	r.broadcast()
+	return nil
+}
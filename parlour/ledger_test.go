@@ -0,0 +1,148 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+func Test_ledgerEntries(t *testing.T) {
+	t.Run("combines payments for the same event", func(t *testing.T) {
+		round := &mahjong.Round{
+			ScoreEvents: []mahjong.ScoreEvent{
+				{Reason: mahjong.ScoreBitten, Payer: 1, Payee: 0, Amount: 2, Time: 1},
+				{Reason: mahjong.ScoreBitten, Payer: 2, Payee: 0, Amount: 2, Time: 1},
+				{Reason: mahjong.ScoreBitten, Payer: 3, Payee: 0, Amount: 2, Time: 1},
+				{Reason: mahjong.ScoreExposedGang, Payer: 0, Payee: 2, Amount: 1, Time: 2},
+				{Reason: mahjong.ScoreExposedGang, Payer: 1, Payee: 2, Amount: 1, Time: 2},
+				{Reason: mahjong.ScoreExposedGang, Payer: 3, Payee: 2, Amount: 1, Time: 2},
+			},
+			Result: &mahjong.Result{
+				Winner:   1,
+				Loser:    3,
+				Payments: [4]int{-1, 4, -1, -2},
+			},
+		}
+		assert.Equal(t, []LedgerEntry{
+			{Round: 2, Reason: LedgerBite, Deltas: [4]int{6, -2, -2, -2}, Time: 1},
+			{Round: 2, Reason: LedgerGang, Deltas: [4]int{-1, -1, 3, -1}, Time: 2},
+			{Round: 2, Reason: LedgerWin, Deltas: [4]int{-1, 4, -1, -2}},
+		}, ledgerEntries(2, round))
+	})
+	t.Run("draw has no win entry", func(t *testing.T) {
+		round := &mahjong.Round{
+			Result: &mahjong.Result{Winner: -1, Loser: -1},
+		}
+		assert.Empty(t, ledgerEntries(0, round))
+	})
+}
+
+func TestRoom_recordRound(t *testing.T) {
+	room := NewRoom(Player{Name: "alice"})
+	room.Round = &mahjong.Round{
+		Scores: [4]int{-1, 4, -1, -2},
+		Result: &mahjong.Result{
+			Winner:   1,
+			Loser:    3,
+			Payments: [4]int{-1, 4, -1, -2},
+		},
+	}
+	room.recordRound()
+	assert.Equal(t, []mahjong.Result{*room.Round.Result}, room.Results)
+	assert.Equal(t, [4]int{-1, 4, -1, -2}, room.Scores)
+	assert.Equal(t, []LedgerEntry{
+		{Round: 0, Reason: LedgerWin, Deltas: [4]int{-1, 4, -1, -2}},
+	}, room.Ledger)
+}
+
+func TestRoom_adjustScores(t *testing.T) {
+	penalty := Adjustment{Reason: LedgerPenalty, Deltas: [4]int{10, -30, 10, 10}}
+	t.Run("not host", func(t *testing.T) {
+		r := newFullRoom()
+		r.Phase = PhaseFinished
+		err := r.adjustScores("id2", penalty, time.Now())
+		assert.EqualError(t, err, "not host")
+	})
+	t.Run("game not started", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.adjustScores("id1", penalty, time.Now())
+		assert.EqualError(t, err, "game not started")
+	})
+	t.Run("tournament", func(t *testing.T) {
+		r := newFullRoom()
+		r.Phase = PhaseFinished
+		r.Tournament = "T1"
+		err := r.adjustScores("id1", penalty, time.Now())
+		assert.Equal(t, errTournamentRoom, err)
+	})
+	t.Run("invalid adjustments", func(t *testing.T) {
+		r := newFullRoom()
+		r.Phase = PhaseFinished
+		for adjustment, message := range map[Adjustment]string{
+			{Reason: LedgerWin, Deltas: [4]int{1, -1}}:           "invalid reason",
+			{Reason: LedgerPenalty, Deltas: [4]int{2, -1, -1}}:   "penalty must be paid by one player",
+			{Reason: LedgerPenalty, Deltas: [4]int{2, -1}}:       "deltas must add up to zero",
+			{Reason: LedgerAdjustment, Deltas: [4]int{}}:         "adjustment has no changes",
+			{Reason: LedgerAdjustment, Deltas: [4]int{1, 1, -1}}: "deltas must add up to zero",
+		} {
+			err := r.adjustScores("id1", adjustment, time.Now())
+			assert.EqualError(t, err, message)
+		}
+		assert.Empty(t, r.Ledger)
+	})
+	t.Run("during a round", func(t *testing.T) {
+		r := newFullRoom()
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		r.Results = []mahjong.Result{{}}
+		r.Scores = [4]int{1, 2, 3, -6}
+		r.Round.Scores = r.Scores
+		err := r.adjustScores("id1", penalty, time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, [4]int{11, -28, 13, 4}, r.Scores)
+		assert.Equal(t, r.Scores, r.Round.Scores)
+		assert.Equal(t, []LedgerEntry{{Round: 1, Reason: LedgerPenalty, Deltas: penalty.Deltas, Time: 1000}}, r.Ledger)
+		assert.Equal(t, RoomEvent{
+			Type:   RoomEventAdjustScores,
+			Time:   1000,
+			Player: "player2",
+			Reason: LedgerPenalty,
+			Deltas: []int{10, -30, 10, 10},
+		}, r.Events[len(r.Events)-1])
+	})
+	t.Run("after a game", func(t *testing.T) {
+		r := newFullRoom()
+		r.Phase = PhaseFinished
+		r.Results = []mahjong.Result{{}, {}}
+		adjustment := Adjustment{Reason: LedgerAdjustment, Deltas: [4]int{-5, -5, 5, 5}}
+		err := r.adjustScores("id1", adjustment, time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, [4]int{-5, -5, 5, 5}, r.Scores)
+		assert.Equal(t, []LedgerEntry{{Round: 1, Reason: LedgerAdjustment, Deltas: adjustment.Deltas, Time: 1000}}, r.Ledger)
+		assert.Empty(t, r.Events[len(r.Events)-1].Player)
+	})
+	t.Run("after a rated game", func(t *testing.T) {
+		r := newRatedRoom()
+		err := r.adjustScores("id1", penalty, time.Now())
+		assert.EqualError(t, err, "game already rated")
+	})
+	t.Run("in the order scores changed", func(t *testing.T) {
+		r := newFullRoom()
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		err := r.adjustScores("id1", penalty, time.Unix(2, 0))
+		assert.NoError(t, err)
+		r.Round.ScoreEvents = []mahjong.ScoreEvent{
+			{Reason: mahjong.ScoreBitten, Payer: 1, Payee: 0, Amount: 2, Time: 1000},
+			{Reason: mahjong.ScoreFlowerSet, Payer: 1, Payee: 2, Amount: 2, Time: 3000},
+		}
+		r.Round.Finished = true
+		r.Round.Result = &mahjong.Result{Winner: -1, Loser: -1, Liable: -1}
+		r.recordRound()
+		var reasons []LedgerReason
+		for _, entry := range r.Ledger {
+			reasons = append(reasons, entry.Reason)
+		}
+		assert.Equal(t, []LedgerReason{LedgerBite, LedgerPenalty, LedgerFlowerSet}, reasons)
+	})
+}
@@ -0,0 +1,143 @@
+package parlour
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// Ruleset is the name of a set of scoring rules which a room can use.
+type Ruleset string
+
+// Possible rulesets.
+const (
+	RulesetDefault Ruleset = "default"
+	RulesetShooter Ruleset = "shooter"
+)
+
+var rulesets = map[Ruleset]mahjong.Rules{
+	RulesetDefault: mahjong.RulesDefault,
+	RulesetShooter: mahjong.RulesShooter,
+}
+
+// rules returns the scoring rules for a ruleset, falling back to the default
+// rules for rooms created before rulesets existed.
+func (r Ruleset) rules() mahjong.Rules {
+	if rules, ok := rulesets[r]; ok {
+		return rules
+	}
+	return mahjong.RulesDefault
+}
+
+// RoomSettings contains the settings a host can change before a game starts.
+type RoomSettings struct {
+	// Public indicates whether a room is listed in the lobby.
+	Public bool `json:"public"`
+
+	// Ruleset is the set of scoring rules to play with.
+	Ruleset Ruleset `json:"ruleset"`
+
+	// Winds is the number of prevailing winds to play, from 1 to 4. Zero
+	// means a full game of four winds.
+	Winds int `json:"winds"`
+
+	// Passcode is required to join a room if set. It must be 6 to 32
+	// characters long.
+	Passcode string `json:"passcode"`
+}
+
+func (s *RoomSettings) validate() error {
+	if s.Ruleset == "" {
+		s.Ruleset = RulesetDefault
+	}
+	if _, ok := rulesets[s.Ruleset]; !ok {
+		return errors.New("invalid ruleset")
+	}
+	if s.Winds < 0 || s.Winds > 4 {
+		return errors.New("invalid number of winds")
+	}
+	if s.Passcode != "" && len(s.Passcode) < 6 {
+		return errors.New("passcode must be at least 6 characters")
+	}
+	if len(s.Passcode) > 32 {
+		return errors.New("passcode too long")
+	}
+	return nil
+}
+
+func (r *Room) updateSettings(playerID string, settings RoomSettings, t time.Time) error {
+	if err := r.checkHost(playerID); err != nil {
+		return err
+	}
+	if r.Phase != PhaseLobby {
+		return errors.New("game already started")
+	}
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	r.Public = settings.Public
+	r.Ruleset = settings.Ruleset
+	r.Winds = settings.Winds
+	r.Passcode = settings.Passcode
+	r.broadcast()
+	return nil
+}
+
+// RoomFilter restricts which rooms are listed in the lobby.
+type RoomFilter struct {
+	// Ruleset only lists rooms using this ruleset if set.
+	Ruleset Ruleset
+}
+
+// RoomListing summarises a room which is open for players to join.
+type RoomListing struct {
+	ID      string  `json:"id"`
+	Host    string  `json:"host"`
+	Players int     `json:"players"`
+	Ruleset Ruleset `json:"ruleset"`
+	Winds   int     `json:"winds"`
+	Stakes  Stakes  `json:"stakes"`
+
+	HasPasscode bool `json:"has_passcode"`
+}
+
+// open returns whether a room should be listed in the lobby.
+func (r *Room) open(filter RoomFilter) bool {
+	if !r.Public || r.Locked || r.Phase != PhaseLobby || len(r.Players) >= 4 {
+		return false
+	}
+	return filter.Ruleset == "" || filter.Ruleset == r.ruleset()
+}
+
+// ruleset returns the ruleset used by a room.
+func (r *Room) ruleset() Ruleset {
+	if r.Ruleset == "" {
+		return RulesetDefault
+	}
+	return r.Ruleset
+}
+
+// winds returns the number of prevailing winds a game in the room lasts for.
+func (r *Room) winds() int {
+	if r.Winds == 0 {
+		return 4
+	}
+	return r.Winds
+}
+
+func (r *Room) listing() RoomListing {
+	listing := RoomListing{
+		ID:      r.ID,
+		Players: len(r.Players),
+		Ruleset: r.ruleset(),
+		Winds:   r.winds(),
+		Stakes:  r.Stakes,
+
+		HasPasscode: r.Passcode != "",
+	}
+	if seat := r.seat(r.Host); seat != -1 {
+		listing.Host = r.Players[seat].Name
+	}
+	return listing
+}
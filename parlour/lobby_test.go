@@ -0,0 +1,51 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+func TestRoom_updateSettings(t *testing.T) {
+	t.Run("not host", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.updateSettings("id2", RoomSettings{Public: true}, time.Now())
+		assert.EqualError(t, err, "not host")
+	})
+	t.Run("invalid ruleset", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.updateSettings("id1", RoomSettings{Ruleset: "riichi"}, time.Now())
+		assert.EqualError(t, err, "invalid ruleset")
+	})
+	t.Run("passcode too short", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.updateSettings("id1", RoomSettings{Passcode: "1234"}, time.Now())
+		assert.EqualError(t, err, "passcode must be at least 6 characters")
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.updateSettings("id1", RoomSettings{Public: true, Ruleset: RulesetShooter}, time.Now())
+		assert.NoError(t, err)
+		assert.True(t, r.Public)
+		assert.Equal(t, RulesetShooter, r.Ruleset)
+
+		_ = r.nextRound(SeatDrawNone, time.Now())
+		assert.Equal(t, mahjong.RulesShooter, r.Round.Rules)
+		err = r.updateSettings("id1", RoomSettings{}, time.Now())
+		assert.EqualError(t, err, "game already started")
+	})
+}
+
+func TestRoom_nextRound_winds(t *testing.T) {
+	r := newFullRoom()
+	r.Winds = 1
+	_ = r.nextRound(SeatDrawNone, time.Now())
+	r.Round.Dealer = 3
+	r.Round.Finished = true
+	r.Round.Result = &mahjong.Result{Winner: -1, Loser: -1, Liable: -1}
+	err := r.nextRound(SeatDrawNone, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, PhaseFinished, r.Phase)
+}
@@ -0,0 +1,249 @@
+package parlour
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultBotWait is how long a player waits in the matchmaking queue before
+// the empty seats at their table are filled with bots.
+const DefaultBotWait = 60 * time.Second
+
+var (
+	errAlreadyQueued = errors.New("already queued")
+	errNotQueued     = errors.New("not queued")
+)
+
+// QueuePreferences describes the kind of game a player wants to be matched
+// into. Only players with the same preferences are seated together.
+type QueuePreferences struct {
+	Ruleset Ruleset `json:"ruleset"`
+	Winds   int     `json:"winds"`
+}
+
+// QueueStatus represents where a player is in the matchmaking process.
+type QueueStatus string
+
+// Possible queue statuses.
+const (
+	QueueNotQueued QueueStatus = "not_queued"
+	QueueWaiting   QueueStatus = "waiting"
+	QueueMatched   QueueStatus = "matched"
+)
+
+// QueueUpdate is sent to players in the matchmaking queue whenever their
+// status changes.
+type QueueUpdate struct {
+	Status QueueStatus `json:"status"`
+
+	// Waiting is the number of players waiting with the same preferences,
+	// including the player receiving the update.
+	Waiting int `json:"waiting,omitempty"`
+
+	// RoomID is the ID of the room a player was seated in once matched.
+	RoomID string `json:"room_id,omitempty"`
+}
+
+type queueEntry struct {
+	Player      Player
+	Preferences QueuePreferences
+	Joined      time.Time
+}
+
+// matchmaker forms tables of four from players waiting in a queue.
+type matchmaker struct {
+	roomService *roomService
+
+	// BotWait is how long the longest waiting player at a table waits before
+	// the empty seats are filled with bots. Zero means tables are never
+	// filled with bots.
+	BotWait time.Duration
+
+	sync.Mutex
+	queue []queueEntry
+
+	// matched contains the ID of the room each matched player was seated in.
+	matched map[string]string
+
+	// clients is a map of subscription channels to player IDs.
+	clients map[chan QueueUpdate]string
+}
+
+func newMatchmaker(roomService *roomService) *matchmaker {
+	return &matchmaker{
+		roomService: roomService,
+		BotWait:     DefaultBotWait,
+		matched:     make(map[string]string),
+		clients:     make(map[chan QueueUpdate]string),
+	}
+}
+
+// Join adds a player to the queue and tries to seat them at a table.
+func (m *matchmaker) Join(player Player, preferences QueuePreferences) error {
+	settings := RoomSettings{Ruleset: preferences.Ruleset, Winds: preferences.Winds}
+	if err := settings.validate(); err != nil {
+		return &Error{error: err}
+	}
+	preferences.Ruleset = settings.Ruleset
+	m.Lock()
+	defer m.Unlock()
+	if m.position(player.ID) != -1 {
+		return &Error{error: errAlreadyQueued}
+	}
+	t := time.Now()
+	m.queue = append(m.queue, queueEntry{
+		Player:      player,
+		Preferences: preferences,
+		Joined:      t,
+	})
+	delete(m.matched, player.ID)
+	m.match(t)
+	m.notifyWaiting()
+	if m.BotWait > 0 {
+		time.AfterFunc(m.BotWait, func() {
+			m.Lock()
+			defer m.Unlock()
+			m.match(time.Now())
+			m.notifyWaiting()
+		})
+	}
+	return nil
+}
+
+// Leave removes a player from the queue.
+func (m *matchmaker) Leave(playerID string) error {
+	m.Lock()
+	defer m.Unlock()
+	i := m.position(playerID)
+	if i == -1 {
+		return &Error{error: errNotQueued}
+	}
+	m.queue = append(m.queue[:i], m.queue[i+1:]...)
+	m.notify(playerID, QueueUpdate{Status: QueueNotQueued})
+	m.notifyWaiting()
+	return nil
+}
+
+// AddClient subscribes a client to queue updates for a player. The player's
+// current status will be immediately sent through ch, which must be buffered.
+// Updates are sent without blocking, so a client which falls behind only
+// receives the latest one.
+func (m *matchmaker) AddClient(playerID string, ch chan QueueUpdate) {
+	m.Lock()
+	defer m.Unlock()
+	m.clients[ch] = playerID
+	sendUpdate(ch, m.status(playerID))
+}
+
+func (m *matchmaker) RemoveClient(ch chan QueueUpdate) {
+	m.Lock()
+	delete(m.clients, ch)
+	m.Unlock()
+}
+
+func (m *matchmaker) position(playerID string) int {
+	for i, entry := range m.queue {
+		if entry.Player.ID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *matchmaker) status(playerID string) QueueUpdate {
+	if i := m.position(playerID); i != -1 {
+		waiting := 0
+		for _, entry := range m.queue {
+			if entry.Preferences == m.queue[i].Preferences {
+				waiting++
+			}
+		}
+		return QueueUpdate{Status: QueueWaiting, Waiting: waiting}
+	}
+	if roomID, ok := m.matched[playerID]; ok {
+		return QueueUpdate{Status: QueueMatched, RoomID: roomID}
+	}
+	return QueueUpdate{Status: QueueNotQueued}
+}
+
+func (m *matchmaker) notify(playerID string, update QueueUpdate) {
+	for ch, clientID := range m.clients {
+		if clientID == playerID {
+			sendUpdate(ch, update)
+		}
+	}
+}
+
+// sendUpdate sends a queue update to a client without blocking while the
+// queue is locked. If the client has not received the previous update yet, it
+// is replaced by the newer one.
+func sendUpdate(ch chan QueueUpdate, update QueueUpdate) {
+	select {
+	case ch <- update:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- update:
+	default:
+	}
+}
+
+func (m *matchmaker) notifyWaiting() {
+	for _, entry := range m.queue {
+		m.notify(entry.Player.ID, m.status(entry.Player.ID))
+	}
+}
+
+// match seats players with the same preferences at tables of four in the
+// order they joined the queue. Players who have waited longer than BotWait
+// are seated with bots instead.
+func (m *matchmaker) match(t time.Time) {
+	var order []QueuePreferences
+	groups := make(map[QueuePreferences][]queueEntry)
+	for _, entry := range m.queue {
+		if _, ok := groups[entry.Preferences]; !ok {
+			order = append(order, entry.Preferences)
+		}
+		groups[entry.Preferences] = append(groups[entry.Preferences], entry)
+	}
+	for _, preferences := range order {
+		entries := groups[preferences]
+		for len(entries) >= 4 {
+			m.seat(entries[:4])
+			entries = entries[4:]
+		}
+		if len(entries) > 0 && m.BotWait > 0 && t.Sub(entries[0].Joined) >= m.BotWait {
+			m.seat(entries)
+		}
+	}
+}
+
+// seat creates a room for entries and removes them from the queue.
+func (m *matchmaker) seat(entries []queueEntry) {
+	players := make([]Player, len(entries))
+	for i, entry := range entries {
+		players[i] = entry.Player
+	}
+	preferences := entries[0].Preferences
+	room, err := m.roomService.CreateTable(players, RoomSettings{
+		Ruleset: preferences.Ruleset,
+		Winds:   preferences.Winds,
+	}, "")
+	if err != nil {
+		fmt.Printf("error creating table: %v\n", err)
+		return
+	}
+	for _, player := range players {
+		i := m.position(player.ID)
+		m.queue = append(m.queue[:i], m.queue[i+1:]...)
+		m.matched[player.ID] = room.ID
+		m.notify(player.ID, QueueUpdate{Status: QueueMatched, RoomID: room.ID})
+	}
+}
@@ -0,0 +1,109 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMatchmaker() *matchmaker {
+	m := newMatchmaker(newRoomService(NewInMemoryRoomRepository()))
+	m.BotWait = 0
+	return m
+}
+
+func TestMatchmaker_Join(t *testing.T) {
+	t.Run("invalid preferences", func(t *testing.T) {
+		m := newTestMatchmaker()
+		err := m.Join(Player{ID: "id1", Name: "player1"}, QueuePreferences{Winds: 5})
+		assert.EqualError(t, err, "invalid number of winds")
+	})
+	t.Run("already queued", func(t *testing.T) {
+		m := newTestMatchmaker()
+		_ = m.Join(Player{ID: "id1", Name: "player1"}, QueuePreferences{})
+		err := m.Join(Player{ID: "id1", Name: "player1"}, QueuePreferences{})
+		assert.EqualError(t, err, "already queued")
+	})
+	t.Run("forms tables of players with the same preferences", func(t *testing.T) {
+		m := newTestMatchmaker()
+		ch := make(chan QueueUpdate, 1)
+		m.AddClient("id1", ch)
+		assert.Equal(t, QueueUpdate{Status: QueueNotQueued}, <-ch)
+
+		_ = m.Join(Player{ID: "id1", Name: "player"}, QueuePreferences{Ruleset: RulesetShooter, Winds: 1})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 1}, <-ch)
+		_ = m.Join(Player{ID: "other", Name: "other"}, QueuePreferences{})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 1}, <-ch)
+		_ = m.Join(Player{ID: "id2", Name: "player"}, QueuePreferences{Ruleset: RulesetShooter, Winds: 1})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 2}, <-ch)
+		_ = m.Join(Player{ID: "id3", Name: "player"}, QueuePreferences{Ruleset: RulesetShooter, Winds: 1})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 3}, <-ch)
+		_ = m.Join(Player{ID: "id4", Name: "player"}, QueuePreferences{Ruleset: RulesetShooter, Winds: 1})
+		update := <-ch
+		assert.Equal(t, QueueMatched, update.Status)
+		assert.Len(t, m.queue, 1)
+
+		room, err := m.roomService.Get(update.RoomID)
+		assert.NoError(t, err)
+		room.RLock()
+		defer room.RUnlock()
+		assert.Equal(t, PhaseInProgress, room.Phase)
+		assert.Equal(t, RulesetShooter, room.Ruleset)
+		assert.Equal(t, 1, room.Winds)
+		assert.ElementsMatch(t, []string{"player", "player 2", "player 3", "player 4"}, []string{
+			room.Players[0].Name,
+			room.Players[1].Name,
+			room.Players[2].Name,
+			room.Players[3].Name,
+		})
+	})
+	t.Run("fills tables with bots after waiting", func(t *testing.T) {
+		m := newTestMatchmaker()
+		m.BotWait = 10 * time.Millisecond
+		ch := make(chan QueueUpdate, 1)
+		m.AddClient("id1", ch)
+		<-ch
+
+		_ = m.Join(Player{ID: "id1", Name: "player1"}, QueuePreferences{})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 1}, <-ch)
+		_ = m.Join(Player{ID: "id2", Name: "player2"}, QueuePreferences{})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 2}, <-ch)
+
+		update := <-ch
+		assert.Equal(t, QueueMatched, update.Status)
+		room, _ := m.roomService.Get(update.RoomID)
+		room.RLock()
+		defer room.RUnlock()
+		bots := 0
+		for _, player := range room.Players {
+			if player.IsBot {
+				bots++
+			}
+		}
+		assert.Equal(t, 2, bots)
+	})
+}
+
+func TestMatchmaker_Leave(t *testing.T) {
+	m := newTestMatchmaker()
+	err := m.Leave("id1")
+	assert.EqualError(t, err, "not queued")
+
+	_ = m.Join(Player{ID: "id1", Name: "player1"}, QueuePreferences{})
+	err = m.Leave("id1")
+	assert.NoError(t, err)
+	assert.Empty(t, m.queue)
+}
+
+func TestMatchmaker_notify(t *testing.T) {
+	t.Run("does not block on stalled clients", func(t *testing.T) {
+		m := newTestMatchmaker()
+		ch := make(chan QueueUpdate, 1)
+		m.AddClient("id1", ch)
+		_ = m.Join(Player{ID: "id1", Name: "player1"}, QueuePreferences{})
+		_ = m.Join(Player{ID: "id2", Name: "player2"}, QueuePreferences{})
+		assert.Equal(t, QueueUpdate{Status: QueueWaiting, Waiting: 2}, <-ch, "client should receive the latest update")
+		assert.Empty(t, ch)
+	})
+}
@@ -47,6 +47,51 @@ func (mr *MockRoomRepositoryMockRecorder) Get(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRoomRepository)(nil).Get), arg0)
 }
 
+// ListByPlayer mocks base method
+func (m *MockRoomRepository) ListByPlayer(arg0 string, arg1 PlayerRoomFilter) ([]RoomSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByPlayer", arg0, arg1)
+	ret0, _ := ret[0].([]RoomSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByPlayer indicates an expected call of ListByPlayer
+func (mr *MockRoomRepositoryMockRecorder) ListByPlayer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByPlayer", reflect.TypeOf((*MockRoomRepository)(nil).ListByPlayer), arg0, arg1)
+}
+
+// ListGames mocks base method
+func (m *MockRoomRepository) ListGames(arg0 string) ([]Game, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGames", arg0)
+	ret0, _ := ret[0].([]Game)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGames indicates an expected call of ListGames
+func (mr *MockRoomRepositoryMockRecorder) ListGames(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGames", reflect.TypeOf((*MockRoomRepository)(nil).ListGames), arg0)
+}
+
+// ListOpen mocks base method
+func (m *MockRoomRepository) ListOpen(arg0 RoomFilter) ([]RoomListing, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpen", arg0)
+	ret0, _ := ret[0].([]RoomListing)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpen indicates an expected call of ListOpen
+func (mr *MockRoomRepositoryMockRecorder) ListOpen(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpen", reflect.TypeOf((*MockRoomRepository)(nil).ListOpen), arg0)
+}
+
 // Save mocks base method
 func (m *MockRoomRepository) Save(arg0 *Room) error {
 	m.ctrl.T.Helper()
@@ -9,7 +9,12 @@ type Parlour struct {
 	RoomRepository RoomRepository
 	SessionStore   sessions.Store
 
-	roomService *roomService
+	roomService       *roomService
+	matchmaker        *matchmaker
+	accountService    *accountService
+	tokenService      *tokenService
+	statsService      *statsService
+	tournamentService *tournamentService
 }
 
 func New(roomRepository RoomRepository, sessionStore sessions.Store) *Parlour {
@@ -18,9 +23,45 @@ func New(roomRepository RoomRepository, sessionStore sessions.Store) *Parlour {
 		SessionStore:   sessionStore,
 	}
 	p.roomService = newRoomService(p.RoomRepository)
+	p.matchmaker = newMatchmaker(p.roomService)
+	p.accountService = newAccountService(NewInMemoryAccountRepository())
+	p.tokenService = newTokenService(NewInMemoryTokenRepository())
+	p.statsService = newStatsService(p.RoomRepository)
+	p.tournamentService = newTournamentService(NewInMemoryTournamentRepository(), p.roomService)
+	p.roomService.tournamentService = p.tournamentService
 	return p
 }
 
+// SetInviteKey sets the key used to sign room invites. If it is not set, a
+// random key is used and invites stop working when the server restarts.
+func (p *Parlour) SetInviteKey(key []byte) {
+	p.roomService.inviter.key = key
+}
+
+// SetAccountRepository sets where player accounts are stored. If it is not
+// set, accounts are only kept in memory.
+func (p *Parlour) SetAccountRepository(accountRepository AccountRepository) {
+	p.accountService.AccountRepository = accountRepository
+}
+
+// SetTokenRepository sets where API tokens are stored. If it is not set,
+// tokens are only kept in memory.
+func (p *Parlour) SetTokenRepository(tokenRepository TokenRepository) {
+	p.tokenService.TokenRepository = tokenRepository
+}
+
+// SetRatingRepository sets where player ratings are stored. If it is not set,
+// ratings are only kept in memory.
+func (p *Parlour) SetRatingRepository(ratingRepository RatingRepository) {
+	p.roomService.ratingService.RatingRepository = ratingRepository
+}
+
+// SetTournamentRepository sets where tournaments are stored. If it is not
+// set, tournaments are only kept in memory.
+func (p *Parlour) SetTournamentRepository(tournamentRepository TournamentRepository) {
+	p.tournamentService.TournamentRepository = tournamentRepository
+}
+
 func (p *Parlour) Run(addr string) error {
 	r := gin.Default()
 	p.configure(r)
@@ -0,0 +1,48 @@
+package parlour
+
+// PlayerView represents a player in a room along with their presence.
+type PlayerView struct {
+	Player
+
+	// Connected indicates whether a player has any open subscriptions to the
+	// room.
+	Connected bool `json:"connected"`
+
+	// DisconnectedSince is the time a player's last subscription was closed.
+	// It is only set if the player is not connected.
+	DisconnectedSince int64 `json:"disconnected_since,omitempty"`
+
+	// BotControlled indicates whether a player's seat is being played by a
+	// bot.
+	BotControlled bool `json:"bot_controlled"`
+
+	// Muted indicates whether a player is prevented from chatting.
+	Muted bool `json:"muted"`
+}
+
+// connected returns whether playerID has any open subscriptions to the room.
+func (r *Room) connected(playerID string) bool {
+	for _, clientID := range r.clients {
+		if clientID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// playerViews returns the players in the room along with their presence.
+func (r *Room) playerViews() []PlayerView {
+	views := make([]PlayerView, len(r.Players))
+	for i, player := range r.Players {
+		views[i] = PlayerView{
+			Player:        player,
+			Connected:     r.connected(player.ID),
+			BotControlled: player.IsBot || player.Substitute != "",
+			Muted:         r.muted(player.ID),
+		}
+		if since, ok := r.disconnected[player.ID]; !views[i].Connected && ok {
+			views[i].DisconnectedSince = since.UnixNano() / 1e6
+		}
+	}
+	return views
+}
@@ -0,0 +1,52 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoom_presence(t *testing.T) {
+	r := NewRoom(Player{ID: "alice", Name: "alice"})
+	_ = r.addPlayer(Player{ID: botNames[0], Name: botNames[0], IsBot: true})
+	players := r.view("alice").Players
+	assert.False(t, players[0].Connected)
+	assert.True(t, players[1].BotControlled)
+
+	alice := make(chan RoomView, 1)
+	r.AddClient("alice", alice)
+	view := <-alice
+	assert.True(t, view.Players[0].Connected)
+	assert.Zero(t, view.Players[0].DisconnectedSince)
+
+	bob := make(chan RoomView, 1)
+	r.AddClient("bob", bob)
+	<-bob
+	assert.Empty(t, alice, "spectators connecting should not be broadcast")
+
+	r.RemoveClient(alice)
+	view = <-bob
+	assert.False(t, view.Players[0].Connected)
+	assert.NotZero(t, view.Players[0].DisconnectedSince)
+
+	r.AddClient("alice", alice)
+	view = <-alice
+	assert.True(t, view.Players[0].Connected)
+	assert.Zero(t, view.Players[0].DisconnectedSince)
+	assert.Len(t, bob, 1)
+}
+
+func TestRoom_broadcast(t *testing.T) {
+	t.Run("does not block on stalled clients", func(t *testing.T) {
+		r := newFullRoom()
+		ch := make(chan RoomView, 1)
+		r.AddClient("id2", ch)
+		_ = r.setLocked("id1", true, time.Now())
+		_ = r.setLocked("id1", false, time.Now())
+		_ = r.setLocked("id1", true, time.Now())
+		view := <-ch
+		assert.True(t, view.Locked, "client should receive the latest view")
+		assert.Empty(t, ch)
+	})
+}
@@ -0,0 +1,60 @@
+package parlour
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter limits how many times something can happen for each key within
+// a sliding window.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	sync.Mutex
+	times map[string][]time.Time
+
+	// swept is when keys without any occurrences in the window were last
+	// removed.
+	swept time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		times:  make(map[string][]time.Time),
+	}
+}
+
+// allow records an occurrence for key at t and returns whether it is within
+// the limit. Occurrences which are not allowed are not recorded.
+func (l *rateLimiter) allow(key string, t time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+	l.sweep(t)
+	times := l.times[key]
+	for len(times) > 0 && t.Sub(times[0]) >= l.window {
+		times = times[1:]
+	}
+	if len(times) >= l.limit {
+		l.times[key] = times
+		return false
+	}
+	l.times[key] = append(times, t)
+	return true
+}
+
+// sweep removes keys whose occurrences are all outside the window, at most
+// once per window.
+func (l *rateLimiter) sweep(t time.Time) {
+	if t.Sub(l.swept) < l.window {
+		return
+	}
+	for key, times := range l.times {
+		if len(times) == 0 || t.Sub(times[len(times)-1]) >= l.window {
+			delete(l.times, key)
+		}
+	}
+	l.swept = t
+}
@@ -0,0 +1,173 @@
+package parlour
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type RatingRepository interface {
+	// Get returns the existing ratings for a ruleset of the players with the
+	// given usernames.
+	Get(ruleset Ruleset, usernames []string) (map[string]Rating, error)
+
+	// Update adds each change to the player's current rating for a ruleset,
+	// or to InitialRating if they have none, and counts another game for
+	// them. Changes are added rather than overwriting the rating so that
+	// games finishing at the same time do not lose each other's updates.
+	Update(ruleset Ruleset, changes []RatingChange) error
+
+	Leaderboard(ruleset Ruleset, limit int) ([]Rating, error)
+}
+
+type ratingKey struct {
+	username string
+	ruleset  Ruleset
+}
+
+type InMemoryRatingRepository struct {
+	sync.RWMutex
+	ratings map[ratingKey]Rating
+}
+
+func NewInMemoryRatingRepository() *InMemoryRatingRepository {
+	return &InMemoryRatingRepository{
+		ratings: map[ratingKey]Rating{},
+	}
+}
+
+func (r *InMemoryRatingRepository) Get(ruleset Ruleset, usernames []string) (map[string]Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
+	ratings := make(map[string]Rating)
+	for _, username := range usernames {
+		if rating, ok := r.ratings[ratingKey{username, ruleset}]; ok {
+			ratings[username] = rating
+		}
+	}
+	return ratings, nil
+}
+
+func (r *InMemoryRatingRepository) Update(ruleset Ruleset, changes []RatingChange) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, change := range changes {
+		if change.Username == "" {
+			continue
+		}
+		key := ratingKey{change.Username, ruleset}
+		rating, ok := r.ratings[key]
+		if !ok {
+			rating = Rating{Username: change.Username, Ruleset: ruleset, Rating: InitialRating}
+		}
+		rating.Rating += change.Delta
+		rating.Games++
+		r.ratings[key] = rating
+	}
+	return nil
+}
+
+func (r *InMemoryRatingRepository) Leaderboard(ruleset Ruleset, limit int) ([]Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
+	ratings := []Rating{}
+	for _, rating := range r.ratings {
+		if rating.Ruleset == ruleset {
+			ratings = append(ratings, rating)
+		}
+	}
+	sort.Slice(ratings, func(i, j int) bool {
+		if ratings[i].Rating != ratings[j].Rating {
+			return ratings[i].Rating > ratings[j].Rating
+		}
+		return ratings[i].Username < ratings[j].Username
+	})
+	if len(ratings) > limit {
+		ratings = ratings[:limit]
+	}
+	return ratings, nil
+}
+
+type PostgresRatingRepository struct {
+	conn Conn
+}
+
+func (p *PostgresRatingRepository) Get(ruleset Ruleset, usernames []string) (map[string]Rating, error) {
+	rows, err := p.conn.Query(context.Background(),
+		"select username, ruleset, rating, games from ratings where ruleset = $1 and username = any($2)",
+		ruleset, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("error getting ratings: %w", err)
+	}
+	defer rows.Close()
+	ratings := make(map[string]Rating)
+	for rows.Next() {
+		var rating Rating
+		err := rows.Scan(&rating.Username, &rating.Ruleset, &rating.Rating, &rating.Games)
+		if err != nil {
+			return nil, fmt.Errorf("error getting ratings: %w", err)
+		}
+		ratings[rating.Username] = rating
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting ratings: %w", err)
+	}
+	return ratings, nil
+}
+
+func (p *PostgresRatingRepository) Update(ruleset Ruleset, changes []RatingChange) error {
+	ctx := context.Background()
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	for _, change := range changes {
+		if change.Username == "" {
+			continue
+		}
+		_, err := tx.Exec(ctx, `insert into ratings (username, ruleset, rating, games)
+values ($1, $2, $3 + $4, 1)
+on conflict (username, ruleset) do update set rating=ratings.rating + $4,
+                                              games=ratings.games + 1`,
+			change.Username,
+			ruleset,
+			InitialRating,
+			change.Delta,
+		)
+		if err != nil {
+			return fmt.Errorf("error saving rating: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresRatingRepository) Leaderboard(ruleset Ruleset, limit int) ([]Rating, error) {
+	rows, err := p.conn.Query(context.Background(),
+		"select username, ruleset, rating, games from ratings where ruleset = $1 order by rating desc, username limit $2",
+		ruleset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting leaderboard: %w", err)
+	}
+	defer rows.Close()
+	ratings := []Rating{}
+	for rows.Next() {
+		var rating Rating
+		err := rows.Scan(&rating.Username, &rating.Ruleset, &rating.Rating, &rating.Games)
+		if err != nil {
+			return nil, fmt.Errorf("error getting leaderboard: %w", err)
+		}
+		ratings = append(ratings, rating)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting leaderboard: %w", err)
+	}
+	return ratings, nil
+}
+
+func NewPostgresRatingRepository(conn Conn) *PostgresRatingRepository {
+	return &PostgresRatingRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,143 @@
+package parlour
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// InitialRating is the rating of a player who has not played any rated
+	// games.
+	InitialRating = 1500
+
+	// ratingK is the most a player's rating can change after a game.
+	ratingK = 32
+)
+
+// Rating is a player's rating for a ruleset.
+type Rating struct {
+	Username string  `json:"username"`
+	Ruleset  Ruleset `json:"ruleset"`
+	Rating   int     `json:"rating"`
+	Games    int     `json:"games"`
+}
+
+// RatingChange is how a player's rating changed after a game.
+type RatingChange struct {
+	Username string `json:"username"`
+	Before   int    `json:"before"`
+	After    int    `json:"after"`
+	Delta    int    `json:"delta"`
+}
+
+// rated returns whether a finished game in the room counts towards ratings.
+// Games with bots, including bots substituting for players who left, are not
+// rated, and neither are games without at least two players with accounts.
+func (r *Room) rated() bool {
+	if len(r.Players) != 4 {
+		return false
+	}
+	accounts := 0
+	for _, player := range r.Players {
+		if player.IsBot || player.Vacated || player.Substitute != "" {
+			return false
+		}
+		if player.Username != "" {
+			accounts++
+		}
+	}
+	return accounts >= 2
+}
+
+// ratingDeltas returns how much each player's rating changes after a game,
+// treating it as a round robin of two-player Elo matches decided by the
+// players' final scores.
+func ratingDeltas(ratings [4]int, scores [4]int) [4]int {
+	var deltas [4]int
+	for i := range ratings {
+		var sum float64
+		for j := range ratings {
+			if i == j {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, float64(ratings[j]-ratings[i])/400))
+			actual := 0.5
+			if scores[i] > scores[j] {
+				actual = 1
+			} else if scores[i] < scores[j] {
+				actual = 0
+			}
+			sum += actual - expected
+		}
+		deltas[i] = int(math.Round(ratingK * sum / 3))
+	}
+	return deltas
+}
+
+type ratingService struct {
+	RatingRepository RatingRepository
+}
+
+func newRatingService(ratingRepository RatingRepository) *ratingService {
+	return &ratingService{
+		RatingRepository: ratingRepository,
+	}
+}
+
+// Changes works out how the ratings of the players with accounts change after
+// a finished game. It does not update their ratings. Guests are treated as
+// having the initial rating.
+func (s *ratingService) Changes(ruleset Ruleset, game Game) ([]RatingChange, error) {
+	var usernames []string
+	for _, player := range game.Players {
+		if player.Username != "" {
+			usernames = append(usernames, player.Username)
+		}
+	}
+	existing, err := s.RatingRepository.Get(ruleset, usernames)
+	if err != nil {
+		return nil, err
+	}
+	var ratings [4]int
+	for i, player := range game.Players {
+		ratings[i] = InitialRating
+		if rating, ok := existing[player.Username]; ok {
+			ratings[i] = rating.Rating
+		}
+	}
+	deltas := ratingDeltas(ratings, game.Scores)
+	changes := make([]RatingChange, len(game.Players))
+	for i, player := range game.Players {
+		if player.Username == "" {
+			continue
+		}
+		changes[i] = RatingChange{
+			Username: player.Username,
+			Before:   ratings[i],
+			After:    ratings[i] + deltas[i],
+			Delta:    deltas[i],
+		}
+	}
+	return changes, nil
+}
+
+// MaxLeaderboardSize is the most players a leaderboard can contain.
+const MaxLeaderboardSize = 100
+
+// Leaderboard returns the highest rated players for a ruleset.
+func (s *ratingService) Leaderboard(ruleset Ruleset, limit int) ([]Rating, error) {
+	if ruleset == "" {
+		ruleset = RulesetDefault
+	}
+	if _, ok := rulesets[ruleset]; !ok {
+		return nil, &Error{error: errors.New("invalid ruleset")}
+	}
+	if limit <= 0 || limit > MaxLeaderboardSize {
+		limit = MaxLeaderboardSize
+	}
+	ratings, err := s.RatingRepository.Leaderboard(ruleset, limit)
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	return ratings, nil
+}
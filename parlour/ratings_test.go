@@ -0,0 +1,181 @@
+package parlour
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRatedRoom() *Room {
+	r := newFullRoom()
+	r.Players[0].Username = "alice"
+	r.Players[1].Username = "bob"
+	r.Players[2].Username = "carol"
+	r.Phase = PhaseFinished
+	r.Scores = [4]int{30, 10, 10, -50}
+	return r
+}
+
+func Test_ratingDeltas(t *testing.T) {
+	t.Run("equal ratings", func(t *testing.T) {
+		deltas := ratingDeltas([4]int{1500, 1500, 1500, 1500}, [4]int{30, 10, 10, -50})
+		assert.Equal(t, [4]int{16, 0, 0, -16}, deltas)
+	})
+	t.Run("upset gains more", func(t *testing.T) {
+		deltas := ratingDeltas([4]int{1300, 1700, 1500, 1500}, [4]int{30, -30, 0, 0})
+		assert.Greater(t, deltas[0], 16)
+		assert.Less(t, deltas[1], -16)
+	})
+}
+
+func TestRoom_rated(t *testing.T) {
+	t.Run("rated", func(t *testing.T) {
+		r := newRatedRoom()
+		assert.True(t, r.rated())
+	})
+	t.Run("not enough accounts", func(t *testing.T) {
+		r := newFullRoom()
+		r.Players[0].Username = "alice"
+		assert.False(t, r.rated())
+	})
+	t.Run("bot", func(t *testing.T) {
+		r := newRatedRoom()
+		r.Players[3].IsBot = true
+		assert.False(t, r.rated())
+	})
+	t.Run("substitute", func(t *testing.T) {
+		r := newRatedRoom()
+		r.Players[3].Substitute = "bot1"
+		assert.False(t, r.rated())
+	})
+}
+
+func Test_ratingService(t *testing.T) {
+	s := newRatingService(NewInMemoryRatingRepository())
+	_ = s.RatingRepository.Update(RulesetDefault, []RatingChange{{Username: "bob", Delta: 100}})
+
+	r := newRatedRoom()
+	changes, err := s.Changes(r.ruleset(), Game{Players: r.Players, Scores: r.Scores})
+	assert.NoError(t, err)
+	assert.NoError(t, s.RatingRepository.Update(r.ruleset(), changes))
+	assert.Len(t, changes, 4)
+	assert.Equal(t, RatingChange{Username: "alice", Before: 1500, After: 1517, Delta: 17}, changes[0])
+	assert.Equal(t, "bob", changes[1].Username)
+	assert.Equal(t, 1600, changes[1].Before)
+	assert.Equal(t, RatingChange{}, changes[3])
+
+	leaderboard, err := s.Leaderboard("", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Rating{
+		{Username: "bob", Ruleset: RulesetDefault, Rating: changes[1].After, Games: 2},
+		{Username: "alice", Ruleset: RulesetDefault, Rating: 1517, Games: 1},
+	}, leaderboard)
+
+	leaderboard, err = s.Leaderboard(RulesetShooter, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, leaderboard)
+
+	_, err = s.Leaderboard("mahjong", 0)
+	assert.EqualError(t, err, "invalid ruleset")
+}
+
+func TestInMemoryRatingRepository_Update(t *testing.T) {
+	repo := NewInMemoryRatingRepository()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = repo.Update(RulesetDefault, []RatingChange{{Username: "alice", Delta: 3}, {}})
+		}()
+	}
+	wg.Wait()
+	ratings, _ := repo.Get(RulesetDefault, []string{"alice"})
+	assert.Equal(t, map[string]Rating{
+		"alice": {Username: "alice", Ruleset: RulesetDefault, Rating: InitialRating + 30, Games: 10},
+	}, ratings)
+}
+
+func Test_roomService_rate(t *testing.T) {
+	t.Run("records rating changes", func(t *testing.T) {
+		s := newRoomService(NewInMemoryRoomRepository())
+		r := newRatedRoom()
+		s.rate(r)
+		assert.Len(t, r.RatingChanges, 4)
+		assert.Equal(t, r.RatingChanges, r.view("id1").RatingChanges)
+	})
+	t.Run("skips bot games", func(t *testing.T) {
+		s := newRoomService(NewInMemoryRoomRepository())
+		r := newRatedRoom()
+		r.Players[3].IsBot = true
+		s.rate(r)
+		assert.Empty(t, r.RatingChanges)
+		leaderboard, _ := s.ratingService.Leaderboard(RulesetDefault, 0)
+		assert.Empty(t, leaderboard)
+	})
+	t.Run("does not update ratings if the room is not saved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		roomRepository := NewMockRoomRepository(ctrl)
+		roomRepository.EXPECT().Save(gomock.Any()).Return(errors.New("error"))
+		s := newRoomService(roomRepository)
+		s.rate(newRatedRoom())
+		leaderboard, _ := s.ratingService.Leaderboard(RulesetDefault, 0)
+		assert.Empty(t, leaderboard)
+	})
+	t.Run("records rating changes for a game that was rematched", func(t *testing.T) {
+		s := newRoomService(NewInMemoryRoomRepository())
+		r := newRatedRoom()
+		s.ratingService.RatingRepository = &rematchingRatingRepository{
+			RatingRepository: s.ratingService.RatingRepository,
+			room:             r,
+		}
+		s.rate(r)
+		assert.Empty(t, r.RatingChanges)
+		assert.Len(t, r.Games[0].RatingChanges, 4)
+	})
+}
+
+// rematchingRatingRepository starts a rematch in a room the first time ratings
+// are looked up.
+type rematchingRatingRepository struct {
+	RatingRepository
+	room *Room
+}
+
+func (r *rematchingRatingRepository) Get(ruleset Ruleset, usernames []string) (map[string]Rating, error) {
+	if len(r.room.Games) == 0 {
+		_ = r.room.rematch(false, time.Now())
+	}
+	return r.RatingRepository.Get(ruleset, usernames)
+}
+
+func TestParlour_leaderboardHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+	parlour.roomService.rate(newRatedRoom())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/leaderboard?limit=1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var ratings []Rating
+	_ = json.Unmarshal(w.Body.Bytes(), &ratings)
+	assert.Equal(t, []Rating{{Username: "alice", Ruleset: RulesetDefault, Rating: 1516, Games: 1}}, ratings)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/leaderboard?limit=abc", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
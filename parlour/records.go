@@ -0,0 +1,411 @@
+package parlour
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// GameRecordVersion is the version of the game record format written by the
+// exporter.
+const GameRecordVersion = 1
+
+// RecordedAction is an action a player took during a round.
+type RecordedAction struct {
+	Seat  int            `json:"seat"`
+	Type  ActionType     `json:"type"`
+	Tiles []mahjong.Tile `json:"tiles,omitempty"`
+
+	// Time is when the action was taken in milliseconds since the Unix epoch.
+	Time int64 `json:"time"`
+}
+
+// RoundRecord contains everything needed to replay a round: the seed its wall
+// was shuffled with and the actions players took.
+type RoundRecord struct {
+	// Game is the index of the game in the room which the round belongs to.
+	Game int `json:"game"`
+
+	Seed   int64             `json:"seed"`
+	Dealer int               `json:"dealer"`
+	Wind   mahjong.Direction `json:"wind"`
+
+	// Start is when the round started in milliseconds since the Unix epoch.
+	Start int64 `json:"start"`
+
+	Actions []RecordedAction `json:"actions"`
+
+	// Result is the outcome of the round. It is nil while the round is in
+	// progress.
+	Result *mahjong.Result `json:"result,omitempty"`
+}
+
+// RecordedPlayer describes a player in a game record.
+type RecordedPlayer struct {
+	Name     string `json:"name"`
+	Username string `json:"username,omitempty"`
+	IsBot    bool   `json:"is_bot,omitempty"`
+}
+
+// GameLog is the record of a single game in a room.
+type GameLog struct {
+	Players []RecordedPlayer `json:"players"`
+	Rounds  []RoundRecord    `json:"rounds"`
+	Scores  [4]int           `json:"scores"`
+
+	// Adjustments contains the penalties and adjustments made by the host,
+	// which are included in Scores.
+	Adjustments []LedgerEntry `json:"adjustments,omitempty"`
+}
+
+// GameRecord is a portable record of the games played in a room.
+type GameRecord struct {
+	Version int     `json:"version"`
+	RoomID  string  `json:"room_id"`
+	Ruleset Ruleset `json:"ruleset"`
+	Winds   int     `json:"winds"`
+	Stakes  Stakes  `json:"stakes"`
+
+	// ReservedDuration is how long in milliseconds other players had to
+	// claim a discard before the next player could draw.
+	ReservedDuration int64 `json:"reserved_duration"`
+
+	Games []GameLog `json:"games"`
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// newSeed returns a seed for dealing a round. Seeds are published in game
+// records, so they must not reveal the seeds of other rounds.
+func newSeed() int64 {
+	var data [8]byte
+	_, err := rand.Read(data[:])
+	if err != nil {
+		panic(err)
+	}
+	return int64(binary.BigEndian.Uint64(data[:]) >> 1)
+}
+
+// startRound deals the current round and starts recording it.
+func (r *Room) startRound(t time.Time) {
+	// the recorded start time is truncated to milliseconds, so the round is
+	// started with the truncated time to be replayed identically
+	t = fromMillis(millis(t))
+	seed := newSeed()
+	r.Round.Start(seed, t)
+	r.Rounds = append(r.Rounds, RoundRecord{
+		Game:    len(r.Games),
+		Seed:    seed,
+		Dealer:  r.Round.Dealer,
+		Wind:    r.Round.Wind,
+		Start:   millis(t),
+		Actions: []RecordedAction{},
+	})
+}
+
+// currentRoundRecord returns the record of the round in progress, or nil if
+// it was started before rounds were recorded.
+func (r *Room) currentRoundRecord() *RoundRecord {
+	if len(r.Rounds) == 0 {
+		return nil
+	}
+	record := &r.Rounds[len(r.Rounds)-1]
+	if record.Result != nil || record.Game != len(r.Games) {
+		return nil
+	}
+	return record
+}
+
+func (r *Room) recordAction(seat int, t time.Time, action Action) {
+	record := r.currentRoundRecord()
+	if record == nil {
+		return
+	}
+	record.Actions = append(record.Actions, RecordedAction{
+		Seat:  seat,
+		Type:  action.Type,
+		Tiles: action.Tiles,
+		Time:  millis(t),
+	})
+}
+
+// exportRecord returns a record of the games played in a room. Only finished
+// rounds are included, and games which started before rounds were recorded
+// are left out because they cannot be replayed.
+func (r *Room) exportRecord() GameRecord {
+	record := GameRecord{
+		Version:          GameRecordVersion,
+		RoomID:           r.ID,
+		Ruleset:          r.ruleset(),
+		Winds:            r.winds(),
+		Stakes:           r.Stakes,
+		ReservedDuration: int64(ReservedDuration / time.Millisecond),
+		Games:            []GameLog{},
+	}
+	for i, game := range r.allGames() {
+		log := GameLog{
+			Players: make([]RecordedPlayer, len(game.Players)),
+			Rounds:  []RoundRecord{},
+			Scores:  game.Scores,
+		}
+		for j, player := range game.Players {
+			log.Players[j] = RecordedPlayer{
+				Name:     player.Name,
+				Username: player.Username,
+				IsBot:    player.IsBot,
+			}
+		}
+		for _, round := range r.Rounds {
+			if round.Game == i && round.Result != nil {
+				log.Rounds = append(log.Rounds, round)
+			}
+		}
+		for _, entry := range game.Ledger {
+			if entry.Reason == LedgerPenalty || entry.Reason == LedgerAdjustment {
+				log.Adjustments = append(log.Adjustments, entry)
+			}
+		}
+		if len(log.Rounds) != len(game.Results) {
+			continue
+		}
+		record.Games = append(record.Games, log)
+	}
+	return record
+}
+
+// deal starts a recorded round with the same wall it was originally dealt.
+func (record GameRecord) deal(round RoundRecord, scores [4]int) *mahjong.Round {
+	r := &mahjong.Round{
+		Scores:           scores,
+		Dealer:           round.Dealer,
+		Wind:             round.Wind,
+		Rules:            record.Ruleset.rules(),
+		ReservedDuration: time.Duration(record.ReservedDuration) * time.Millisecond,
+	}
+	r.Start(round.Seed, fromMillis(round.Start))
+	return r
+}
+
+// replayActions applies the recorded actions in a round to a dealt round.
+func replayActions(r *mahjong.Round, round RoundRecord) error {
+	for i, action := range round.Actions {
+		if action.Type == ActionVoidRound {
+			r.Void(fromMillis(action.Time))
+			continue
+		}
+		err := applyAction(r, action.Seat, fromMillis(action.Time), Action{Type: action.Type, Tiles: action.Tiles})
+		if err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func sameResult(a, b *mahjong.Result) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Dealer == b.Dealer &&
+		a.Wind == b.Wind &&
+		a.Winner == b.Winner &&
+		a.Loser == b.Loser &&
+		a.Points == b.Points &&
+		a.Liable == b.Liable &&
+		a.Payments == b.Payments &&
+		a.Voided == b.Voided
+}
+
+// replay rebuilds every round in a game record through the engine, and
+// verifies that they have the recorded results and final scores.
+func (record GameRecord) replay() ([][]*mahjong.Round, error) {
+	games := make([][]*mahjong.Round, len(record.Games))
+	for i, game := range record.Games {
+		var scores [4]int
+		for j, round := range game.Rounds {
+			r := record.deal(round, scores)
+			err := replayActions(r, round)
+			if err != nil {
+				return nil, fmt.Errorf("game %d round %d: %w", i+1, j+1, err)
+			}
+			if !r.Finished || !sameResult(r.Result, round.Result) {
+				return nil, fmt.Errorf("game %d round %d: result does not match record", i+1, j+1)
+			}
+			games[i] = append(games[i], r)
+			scores = r.Scores
+		}
+		for _, adjustment := range game.Adjustments {
+			for seat, delta := range adjustment.Deltas {
+				scores[seat] += delta
+			}
+		}
+		if scores != game.Scores {
+			return nil, fmt.Errorf("game %d: scores do not match record", i+1)
+		}
+	}
+	return games, nil
+}
+
+// ImportGameRecord reads a game record and rebuilds its rounds through the
+// engine, returning an error if the record's results cannot be reproduced.
+// The rebuilt rounds are returned by game.
+func ImportGameRecord(data []byte) (*GameRecord, [][]*mahjong.Round, error) {
+	var record GameRecord
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid game record: %w", err)
+	}
+	if record.Version != GameRecordVersion {
+		return nil, nil, fmt.Errorf("unsupported game record version: %d", record.Version)
+	}
+	if _, ok := rulesets[record.Ruleset]; !ok {
+		return nil, nil, errors.New("invalid ruleset")
+	}
+	rounds, err := record.replay()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &record, rounds, nil
+}
+
+var directionNames = map[mahjong.Direction]string{
+	mahjong.DirectionEast:  "East",
+	mahjong.DirectionSouth: "South",
+	mahjong.DirectionWest:  "West",
+	mahjong.DirectionNorth: "North",
+}
+
+// tileName returns the name of a tile without the prefix used to sort it.
+func tileName(tile mahjong.Tile) string {
+	return strings.TrimLeft(string(tile), "0123456789")
+}
+
+func tileNames(tiles []mahjong.Tile) string {
+	names := make([]string, len(tiles))
+	for i, tile := range tiles {
+		names[i] = tileName(tile)
+	}
+	return strings.Join(names, " ")
+}
+
+func concealedTiles(bag mahjong.TileBag) []mahjong.Tile {
+	var tiles []mahjong.Tile
+	for tile, count := range bag {
+		for i := 0; i < count; i++ {
+			tiles = append(tiles, tile)
+		}
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		return tiles[i] < tiles[j]
+	})
+	return tiles
+}
+
+func describeAction(name string, action RecordedAction) string {
+	switch action.Type {
+	case ActionDraw:
+		return name + " draws"
+	case ActionDiscard:
+		return name + " discards " + tileNames(action.Tiles)
+	case ActionChi:
+		return name + " chis with " + tileNames(action.Tiles)
+	case ActionPong:
+		return name + " pongs"
+	case ActionGang:
+		if len(action.Tiles) > 0 {
+			return name + " gangs " + tileNames(action.Tiles)
+		}
+		return name + " gangs"
+	case ActionHu:
+		return name + " declares a win"
+	case ActionEndRound:
+		return name + " ends the round"
+	case ActionVoidRound:
+		return "The host voids the round"
+	}
+	return name + " " + string(action.Type)
+}
+
+func describeResult(players []RecordedPlayer, result *mahjong.Result) string {
+	if result.Voided {
+		return "The round is voided."
+	}
+	if result.Winner == -1 {
+		return "The round ends in a draw."
+	}
+	var breakdown []string
+	for _, tai := range result.Breakdown {
+		breakdown = append(breakdown, fmt.Sprintf("%s %d", tai.Element, tai.Points))
+	}
+	how := "by self-draw"
+	if result.Loser != -1 {
+		how = "on " + players[result.Loser].Name + "'s discard"
+	}
+	return fmt.Sprintf("%s wins %s for %d points (%s).", players[result.Winner].Name, how, result.Points, strings.Join(breakdown, ", "))
+}
+
+func describeScores(players []RecordedPlayer, scores [4]int) string {
+	parts := make([]string, len(players))
+	for i, player := range players {
+		parts[i] = fmt.Sprintf("%s %d", player.Name, scores[i])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeTranscript writes a human-readable transcript of a game record,
+// including each player's starting hand in every round.
+func writeTranscript(w io.Writer, record GameRecord) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Room %s\n", record.RoomID)
+	fmt.Fprintf(&b, "Ruleset: %s\n", record.Ruleset)
+	fmt.Fprintf(&b, "Winds: %d\n", record.Winds)
+	for i, game := range record.Games {
+		fmt.Fprintf(&b, "\nGame %d\n", i+1)
+		var scores [4]int
+		for j, round := range game.Rounds {
+			fmt.Fprintf(&b, "\nRound %d: %s wind, %s deals\n", j+1, directionNames[round.Wind], game.Players[round.Dealer].Name)
+			r := record.deal(round, scores)
+			for seat, hand := range r.Hands {
+				fmt.Fprintf(&b, "  %s: %s", game.Players[seat].Name, tileNames(concealedTiles(hand.Concealed)))
+				if len(hand.Flowers) > 0 {
+					fmt.Fprintf(&b, " [%s]", tileNames(hand.Flowers))
+				}
+				b.WriteString("\n")
+			}
+			for _, action := range round.Actions {
+				var name string
+				if action.Seat != -1 {
+					name = game.Players[action.Seat].Name
+				}
+				fmt.Fprintf(&b, "  %s\n", describeAction(name, action))
+			}
+			err := replayActions(r, round)
+			if err != nil {
+				return fmt.Errorf("game %d round %d: %w", i+1, j+1, err)
+			}
+			scores = r.Scores
+			if round.Result != nil {
+				fmt.Fprintf(&b, "  %s\n", describeResult(game.Players, round.Result))
+			}
+		}
+		for _, adjustment := range game.Adjustments {
+			fmt.Fprintf(&b, "\nRound %d %s: %s\n", adjustment.Round+1, adjustment.Reason, describeScores(game.Players, adjustment.Deltas))
+		}
+		fmt.Fprintf(&b, "\nFinal scores: %s\n", describeScores(game.Players, game.Scores))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
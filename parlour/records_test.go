@@ -0,0 +1,218 @@
+package parlour
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// claim tries to win on or pong the last discard for every player except
+// the one who discarded it.
+func claim(r *Room, t time.Time) bool {
+	for _, action := range []ActionType{ActionHu, ActionPong} {
+		for seat := 0; seat < 4; seat++ {
+			if r.reduceRound(seat, t, Action{Type: action}) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leastCommonTile returns the tile a player holds the fewest copies of.
+func leastCommonTile(hand mahjong.TileBag) mahjong.Tile {
+	tiles := concealedTiles(hand)
+	best := tiles[0]
+	for _, tile := range tiles {
+		if hand.Count(tile) < hand.Count(best) {
+			best = tile
+		}
+	}
+	return best
+}
+
+// playGame plays the game in progress in a room to completion. Players win
+// whenever they can, pong every discard they can and otherwise discard the
+// tile they hold the fewest copies of.
+func playGame(t *testing.T, r *Room) {
+	now := time.Now()
+	var err error
+	for err == nil && r.Phase == PhaseInProgress {
+		now = now.Add(3 * time.Second)
+		round := r.Round
+		seat := round.Turn
+		switch {
+		case round.Finished:
+			err = r.nextRound(SeatDrawNone, now)
+		case round.Phase == mahjong.PhaseDraw && claim(r, now):
+		case round.Phase == mahjong.PhaseDraw:
+			err = r.reduceRound(seat, now, Action{Type: ActionDraw})
+		case r.reduceRound(seat, now, Action{Type: ActionHu}) == nil:
+		case len(round.Wall) < mahjong.MinTilesLeft:
+			err = r.reduceRound(seat, now, Action{Type: ActionEndRound})
+		default:
+			tile := leastCommonTile(round.Hands[seat].Concealed)
+			err = r.reduceRound(seat, now, Action{Type: ActionDiscard, Tiles: []mahjong.Tile{tile}})
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newPlayedRoom(t *testing.T) *Room {
+	r := newFullRoom()
+	r.ID = "ABCD"
+	r.Winds = 1
+	if err := r.nextRound(SeatDrawNone, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	playGame(t, r)
+	return r
+}
+
+func TestRoom_exportRecord(t *testing.T) {
+	r := newPlayedRoom(t)
+	record := r.exportRecord()
+	assert.Equal(t, GameRecordVersion, record.Version)
+	assert.Equal(t, "ABCD", record.RoomID)
+	assert.Equal(t, int64(2000), record.ReservedDuration)
+	if assert.Len(t, record.Games, 1) {
+		game := record.Games[0]
+		assert.Equal(t, "player1", game.Players[0].Name)
+		assert.Equal(t, r.Scores, game.Scores)
+		assert.Len(t, game.Rounds, len(r.Results))
+		for i, round := range game.Rounds {
+			assert.Equal(t, r.Results[i], *round.Result)
+			assert.NotEmpty(t, round.Actions)
+		}
+	}
+
+	t.Run("leaves out unrecorded games", func(t *testing.T) {
+		r := newPlayedRoom(t)
+		r.Rounds = nil
+		assert.Empty(t, r.exportRecord().Games)
+	})
+	t.Run("includes rematches", func(t *testing.T) {
+		r := newPlayedRoom(t)
+		if err := r.rematch(false, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		playGame(t, r)
+		assert.Len(t, r.exportRecord().Games, 2)
+	})
+}
+
+func TestImportGameRecord(t *testing.T) {
+	r := newPlayedRoom(t)
+	data, _ := json.Marshal(r.exportRecord())
+
+	t.Run("replays rounds", func(t *testing.T) {
+		record, rounds, err := ImportGameRecord(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "ABCD", record.RoomID)
+		if assert.Len(t, rounds, 1) && assert.Len(t, rounds[0], len(r.Results)) {
+			last := rounds[0][len(rounds[0])-1]
+			assert.Equal(t, r.Scores, last.Scores)
+		}
+	})
+	t.Run("detects tampered results", func(t *testing.T) {
+		var record GameRecord
+		_ = json.Unmarshal(data, &record)
+		result := record.Games[0].Rounds[0].Result
+		result.Points++
+		tampered, _ := json.Marshal(record)
+		_, _, err := ImportGameRecord(tampered)
+		assert.EqualError(t, err, "game 1 round 1: result does not match record")
+	})
+	t.Run("detects tampered scores", func(t *testing.T) {
+		var record GameRecord
+		_ = json.Unmarshal(data, &record)
+		record.Games[0].Scores[0]++
+		tampered, _ := json.Marshal(record)
+		_, _, err := ImportGameRecord(tampered)
+		assert.EqualError(t, err, "game 1: scores do not match record")
+	})
+	t.Run("includes adjustments", func(t *testing.T) {
+		r := newPlayedRoom(t)
+		adjustment := Adjustment{Reason: LedgerPenalty, Deltas: [4]int{-30, 10, 10, 10}}
+		if err := r.adjustScores("id1", adjustment, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(r.exportRecord())
+		record, _, err := ImportGameRecord(data)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []LedgerEntry{r.Ledger[len(r.Ledger)-1]}, record.Games[0].Adjustments)
+			assert.Equal(t, LedgerPenalty, record.Games[0].Adjustments[0].Reason)
+		}
+	})
+	t.Run("replays voided rounds", func(t *testing.T) {
+		r := newFullRoom()
+		r.Winds = 1
+		if err := r.nextRound(SeatDrawNone, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.voidRound("id1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		playGame(t, r)
+		data, _ := json.Marshal(r.exportRecord())
+		record, rounds, err := ImportGameRecord(data)
+		if assert.NoError(t, err) {
+			assert.True(t, rounds[0][0].Result.Voided)
+			var b strings.Builder
+			assert.NoError(t, writeTranscript(&b, *record))
+			assert.Contains(t, b.String(), "  The host voids the round\n  The round is voided.\n")
+		}
+	})
+	t.Run("rejects other versions", func(t *testing.T) {
+		_, _, err := ImportGameRecord([]byte(`{"version": 2}`))
+		assert.EqualError(t, err, "unsupported game record version: 2")
+	})
+}
+
+func Test_writeTranscript(t *testing.T) {
+	r := newPlayedRoom(t)
+	var b strings.Builder
+	err := writeTranscript(&b, r.exportRecord())
+	assert.NoError(t, err)
+	transcript := b.String()
+	assert.True(t, strings.HasPrefix(transcript, "Room ABCD\nRuleset: default\nWinds: 1\n"))
+	assert.Contains(t, transcript, "Round 1: East wind, player1 deals\n")
+	assert.Contains(t, transcript, "  player1 discards ")
+	assert.Contains(t, transcript, "Final scores: player1 ")
+}
+
+func TestParlour_exportRecordHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	repo := NewInMemoryRoomRepository()
+	r := newPlayedRoom(t)
+	_ = repo.Save(r)
+	parlour := New(repo, memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/rooms/ABCD/record", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, _, err := ImportGameRecord(w.Body.Bytes())
+	assert.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/rooms/ABCD/transcript", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Room ABCD")
+}
@@ -2,7 +2,7 @@ package parlour
 
 import (
 	"errors"
-	"math/rand"
+	"fmt"
 	"sync"
 	"time"
 
@@ -17,6 +17,10 @@ const (
 	PhaseFinished
 )
 
+// ReservedDuration is how long other players have to claim a discard before
+// the next player may draw.
+const ReservedDuration = 2 * time.Second
+
 var (
 	errRoomFull     = errors.New("room full")
 	errNotInRoom    = errors.New("not in room")
@@ -28,6 +32,17 @@ type Player struct {
 	ID    string `json:"id"`
 	Name  string `json:"name"`
 	IsBot bool   `json:"is_bot"`
+
+	// Username is the username of the player's account, if they are signed
+	// in. Only players with accounts have ratings.
+	Username string `json:"username,omitempty"`
+
+	// Vacated indicates whether a player left their seat during a game.
+	Vacated bool `json:"vacated,omitempty"`
+
+	// Substitute is the ID of the bot playing in place of a player who
+	// vacated their seat.
+	Substitute string `json:"substitute,omitempty"`
 }
 
 type Room struct {
@@ -39,21 +54,112 @@ type Room struct {
 	Scores  [4]int
 	Results []mahjong.Result
 
+	// Host is the ID of the player who owns the room.
+	Host string
+
+	// Locked indicates whether new players are prevented from joining.
+	Locked bool
+
+	// Public indicates whether the room is listed in the lobby.
+	Public bool
+
+	// Ruleset is the set of scoring rules used in the room.
+	Ruleset Ruleset
+
+	// Winds is the number of prevailing winds a game lasts for. Zero means a
+	// full game of four winds.
+	Winds int
+
+	// Passcode is required to join the room unless a player has an invite.
+	// Rooms without a passcode can be joined by anyone.
+	Passcode string
+
+	// Ledger contains every change in scores in the order they happened.
+	Ledger []LedgerEntry
+
+	// Stakes describes how much the game in the room is played for.
+	Stakes Stakes
+
+	// Games contains the previously completed games in the room.
+	Games []Game
+
+	// Rounds contains the records of every round started in the room, which
+	// are used to replay them.
+	Rounds []RoundRecord
+
+	// Tournament is the ID of the tournament the room is a table in, if any.
+	Tournament string
+
+	// RatingChanges contains how each player's rating changed after the game
+	// in the room finished, if it was rated.
+	RatingChanges []RatingChange
+
+	// Events contains the room events which happened in the room.
+	Events []RoomEvent
+
+	// Chat contains the most recent chat messages posted in the room.
+	Chat []ChatMessage
+
+	// Muted contains the IDs of players who are not allowed to chat.
+	Muted []string
+
 	sync.RWMutex
 
 	// clients is a map of subscription channels to player IDs.
 	clients map[chan RoomView]string
+
+	// disconnected contains the time each player's last subscription was
+	// closed.
+	disconnected map[string]time.Time
+
+	// takeOvers contains the timers which hand vacated seats over to bots,
+	// by the ID of the player who vacated them.
+	takeOvers map[string]*time.Timer
+
+	// savedMembers contains the members of the room when it was last saved,
+	// so that the index of rooms each player has been in is only updated
+	// when they change.
+	savedMembers []string
 }
 
 type RoomView struct {
 	ID      string             `json:"id"`
 	Nonce   int                `json:"nonce"`
 	Phase   Phase              `json:"phase"`
-	Players []Player           `json:"players"`
+	Players []PlayerView       `json:"players"`
 	Round   *mahjong.RoundView `json:"round,omitempty"`
 	Scores  [4]int             `json:"scores"`
 	Results []mahjong.Result   `json:"results"`
+	Ledger  []LedgerEntry      `json:"ledger"`
 	Inside  bool               `json:"inside"`
+	Host    string             `json:"host"`
+	Locked  bool               `json:"locked"`
+	Public  bool               `json:"public"`
+	Ruleset Ruleset            `json:"ruleset"`
+	Winds   int                `json:"winds"`
+
+	// Passcode is only shown to the host.
+	Passcode    string `json:"passcode,omitempty"`
+	HasPasscode bool   `json:"has_passcode"`
+
+	Stakes     Stakes      `json:"stakes"`
+	Settlement *Settlement `json:"settlement,omitempty"`
+	Games      []Game      `json:"games"`
+	Events     []RoomEvent `json:"events"`
+
+	RatingChanges []RatingChange `json:"rating_changes,omitempty"`
+
+	Chat []ChatMessage `json:"chat"`
+}
+
+// Game represents a completed game in a room.
+type Game struct {
+	Players []Player         `json:"players"`
+	Scores  [4]int           `json:"scores"`
+	Results []mahjong.Result `json:"results"`
+	Ledger  []LedgerEntry    `json:"ledger"`
+
+	RatingChanges []RatingChange `json:"rating_changes,omitempty"`
 }
 
 func (r *Room) WithLock(f func(r *Room)) {
@@ -68,9 +174,11 @@ func (r *Room) WithRLock(f func(r *Room)) {
 	r.RUnlock()
 }
 
+// seat returns the seat of a player, or of the player a bot is substituting
+// for, or -1 if playerID is not in the room.
 func (r *Room) seat(playerID string) int {
 	for i, player := range r.Players {
-		if player.ID == playerID {
+		if player.ID == playerID || player.Substitute == playerID {
 			return i
 		}
 	}
@@ -83,19 +191,79 @@ func (r *Room) view(playerID string) RoomView {
 		ID:      r.ID,
 		Nonce:   r.Nonce,
 		Phase:   r.Phase,
-		Players: r.Players,
+		Players: r.playerViews(),
+		Scores:  r.Scores,
 		Results: r.Results,
+		Ledger:  r.Ledger,
 		Inside:  r.seat(playerID) != -1,
+		Host:    r.Host,
+		Locked:  r.Locked,
+		Public:  r.Public,
+		Ruleset: r.ruleset(),
+		Winds:   r.winds(),
+		Stakes:  r.Stakes,
+		Games:   r.Games,
+		Events:  r.Events,
+		Chat:    r.Chat,
+	}
+	if r.Phase == PhaseFinished {
+		view.RatingChanges = r.RatingChanges
+	}
+	view.HasPasscode = r.Passcode != ""
+	if playerID == r.Host {
+		view.Passcode = r.Passcode
 	}
 	if r.Phase == PhaseInProgress {
 		roundView := r.Round.View(r.seat(playerID))
 		view.Round = &roundView
 	}
+	if r.Phase == PhaseFinished && r.Stakes.PointValue > 0 {
+		settlement := settle(r.Scores, r.Stakes)
+		view.Settlement = &settlement
+	}
 	return view
 }
 
+func (r *Room) setStakes(playerID string, stakes Stakes) error {
+	if r.seat(playerID) == -1 {
+		return errNotInRoom
+	}
+	if r.Phase != PhaseLobby {
+		return errors.New("game already started")
+	}
+	if err := stakes.validate(); err != nil {
+		return err
+	}
+	r.Stakes = stakes
+	r.broadcast()
+	return nil
+}
+
+// uniqueName returns name, or name with a number appended if another player
+// in the room already has it.
+func (r *Room) uniqueName(name string) string {
+	unique := name
+	for i := 2; ; i++ {
+		taken := false
+		for _, player := range r.Players {
+			if player.Name == unique {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return unique
+		}
+		unique = fmt.Sprintf("%s %d", name, i)
+	}
+}
+
 func (r *Room) addPlayer(player Player) error {
-	for _, p := range r.Players {
+	for i, p := range r.Players {
+		if p.ID == player.ID && p.Vacated {
+			r.reclaimSeat(i, time.Now())
+			return nil
+		}
 		if p.Name == player.Name {
 			if p.ID == player.ID {
 				return nil
@@ -106,6 +274,9 @@ func (r *Room) addPlayer(player Player) error {
 	if len(r.Players) == 4 {
 		return errors.New("room full")
 	}
+	if r.Locked {
+		return errRoomLocked
+	}
 	r.Players = append(r.Players, player)
 	r.broadcast()
 	return nil
@@ -122,60 +293,87 @@ const (
 	ActionGang      ActionType = "gang"
 	ActionHu        ActionType = "hu"
 	ActionEndRound  ActionType = "end"
+	ActionRematch   ActionType = "rematch"
+
+	// ActionVoidRound is recorded when the host voids a round. Players
+	// cannot take it.
+	ActionVoidRound ActionType = "void"
 )
 
 type Action struct {
 	Nonce int            `json:"nonce"`
 	Type  ActionType     `json:"type"`
 	Tiles []mahjong.Tile `json:"tiles"`
+
+	// Shuffle indicates whether seats should be reshuffled for a rematch.
+	Shuffle bool `json:"shuffle,omitempty"`
+
+	// SeatDraw is the method used to assign seats when starting a game.
+	SeatDraw SeatDraw `json:"seat_draw,omitempty"`
 }
 
-func (r *Room) reduceRound(seat int, t time.Time, action Action) error {
-	if r.Phase != PhaseInProgress {
-		return errors.New("invalid action")
-	}
+// applyAction applies a player's action to a round.
+func applyAction(round *mahjong.Round, seat int, t time.Time, action Action) error {
 	switch action.Type {
 	case ActionDraw:
-		return r.Round.Draw(seat, t)
+		return round.Draw(seat, t)
 	case ActionDiscard:
 		if len(action.Tiles) < 1 {
 			return errors.New("tiles is required")
 		}
-		return r.Round.Discard(seat, t, action.Tiles[0])
+		return round.Discard(seat, t, action.Tiles[0])
 	case ActionChi:
 		if len(action.Tiles) < 2 {
 			return errors.New("tiles is too short")
 		}
-		return r.Round.Chi(seat, t, action.Tiles[0], action.Tiles[1])
+		return round.Chi(seat, t, action.Tiles[0], action.Tiles[1])
 	case ActionPong:
-		return r.Round.Pong(seat, t)
+		return round.Pong(seat, t)
 	case ActionGang:
 		if len(action.Tiles) > 0 {
-			return r.Round.GangFromHand(seat, t, action.Tiles[0])
+			return round.GangFromHand(seat, t, action.Tiles[0])
 		}
-		return r.Round.GangFromDiscard(seat, t)
+		return round.GangFromDiscard(seat, t)
 	case ActionHu:
-		return r.Round.Hu(seat, t)
+		return round.Hu(seat, t)
 	case ActionEndRound:
-		return r.Round.End(seat, t)
+		return round.End(seat, t)
 	default:
 		return errors.New("action is invalid")
 	}
 }
 
+func (r *Room) reduceRound(seat int, t time.Time, action Action) error {
+	if r.Phase != PhaseInProgress {
+		return errors.New("invalid action")
+	}
+	err := applyAction(r.Round, seat, t, action)
+	if err != nil {
+		return err
+	}
+	r.recordAction(seat, t, action)
+	return nil
+}
+
 func (r *Room) reduce(playerID string, action Action) error {
 	seat := r.seat(playerID)
 	if seat == -1 {
 		return errForbidden
 	}
+	if r.Players[seat].Vacated && r.Players[seat].ID == playerID {
+		return errSeatVacated
+	}
 	if action.Nonce != r.Nonce {
 		return errInvalidNonce
 	}
 	t := time.Now()
 	var err error
-	if action.Type == ActionNextRound {
-		err = r.nextRound()
-	} else {
+	switch action.Type {
+	case ActionNextRound:
+		err = r.nextRound(action.SeatDraw, t)
+	case ActionRematch:
+		err = r.rematch(action.Shuffle, t)
+	default:
 		err = r.reduceRound(seat, t, action)
 	}
 	if err != nil {
@@ -187,56 +385,120 @@ func (r *Room) reduce(playerID string, action Action) error {
 }
 
 // AddClient subscribes a new client to the room. The current room state will
-// be immediately sent through ch, so either ensure ch is buffered or read from
-// ch concurrently to prevent deadlock.
+// be immediately sent through ch, which must be buffered. Views are sent
+// without blocking, so a client which falls behind only receives the latest
+// one.
 func (r *Room) AddClient(playerID string, ch chan RoomView) {
 	r.Lock()
 	defer r.Unlock()
+	connected := r.connected(playerID)
 	r.clients[ch] = playerID
-	view := r.view(playerID)
-	ch <- view
+	if !connected && r.seat(playerID) != -1 {
+		delete(r.disconnected, playerID)
+		r.broadcast()
+		return
+	}
+	sendView(ch, r.view(playerID))
 }
 
 func (r *Room) RemoveClient(ch chan RoomView) {
 	r.Lock()
+	defer r.Unlock()
+	playerID, ok := r.clients[ch]
+	if !ok {
+		return
+	}
 	delete(r.clients, ch)
-	r.Unlock()
+	if !r.connected(playerID) && r.seat(playerID) != -1 {
+		r.disconnected[playerID] = time.Now()
+		r.broadcast()
+	}
 }
 
 func (r *Room) broadcast() {
 	for ch, playerID := range r.clients {
-		view := r.view(playerID)
-		ch <- view
+		sendView(ch, r.view(playerID))
+	}
+}
+
+// sendView sends a view to a client without blocking while the room is
+// locked. If the client has not received the previous view yet, it is
+// replaced by the newer one.
+func sendView(ch chan RoomView, view RoomView) {
+	select {
+	case ch <- view:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- view:
+	default:
 	}
 }
 
-func (r *Room) removePlayer(playerID string) {
+// removePlayer removes a player from the room. If a game is in progress, the
+// player's seat is vacated instead so that it can be taken over by a bot or
+// reclaimed later. It returns whether the seat was vacated.
+func (r *Room) removePlayer(playerID string, t time.Time) bool {
+	seat := -1
 	for i, player := range r.Players {
 		if player.ID == playerID {
-			r.Players = append(r.Players[:i], r.Players[i+1:]...)
-			r.broadcast()
-			return
+			seat = i
+			break
+		}
+	}
+	if seat == -1 {
+		return false
+	}
+	vacated := r.Phase == PhaseInProgress
+	if vacated {
+		r.vacateSeat(seat, t)
+	} else {
+		if r.Players[seat].Substitute != "" {
+			r.stopBot(r.Players[seat].Substitute)
 		}
+		r.Players = append(r.Players[:seat], r.Players[seat+1:]...)
 	}
+	if r.Host == playerID {
+		r.Host = ""
+		for _, p := range r.Players {
+			if !p.IsBot && !p.Vacated {
+				r.Host = p.ID
+				break
+			}
+		}
+	}
+	r.broadcast()
+	return vacated
 }
 
-func (r *Room) nextRound() error {
+func (r *Room) nextRound(seatDraw SeatDraw, t time.Time) error {
 	if r.Phase == PhaseLobby {
 		if len(r.Players) < 4 {
 			return errors.New("not enough players")
 		}
+		err := r.drawSeats(seatDraw, t)
+		if err != nil {
+			return err
+		}
 		r.Phase = PhaseInProgress
 		r.Round = &mahjong.Round{
-			Rules:            mahjong.RulesDefault,
-			ReservedDuration: 2 * time.Second,
+			Rules:            r.ruleset().rules(),
+			ReservedDuration: ReservedDuration,
 		}
-		r.Round.Start(rand.Int63(), time.Now())
+		r.startRound(t)
 		return nil
 	}
 	next, err := r.Round.Next()
+	if err == nil && int(next.Wind) >= r.winds() {
+		err = mahjong.ErrNoMoreRounds
+	}
 	if err == mahjong.ErrNoMoreRounds {
-		r.Scores = r.Round.Scores
-		r.Results = append(r.Results, *r.Round.Result)
+		r.recordRound()
 		r.Phase = PhaseFinished
 		r.Round = nil
 		return nil
@@ -244,18 +506,75 @@ func (r *Room) nextRound() error {
 	if err != nil {
 		return err
 	}
-	r.Results = append(r.Results, *r.Round.Result)
+	r.recordRound()
 	r.Round = next
-	r.Round.Start(rand.Int63(), time.Now())
+	r.startRound(t)
 	return nil
 }
 
+// rematch archives a finished game and starts a new one with the same
+// players, optionally reshuffling their seats.
+func (r *Room) rematch(shuffle bool, t time.Time) error {
+	if r.Phase != PhaseFinished {
+		return errors.New("game not finished")
+	}
+	if r.Tournament != "" {
+		// the table's result is recorded from the room's final scores
+		return errTournamentRoom
+	}
+	// check that the next game can start before archiving this one, so that
+	// the room is left untouched if it cannot
+	if len(r.Players) < 4 {
+		return errors.New("not enough players")
+	}
+	players := make([]Player, len(r.Players))
+	copy(players, r.Players)
+	r.Games = append(r.Games, Game{
+		Players: players,
+		Scores:  r.Scores,
+		Results: r.Results,
+		Ledger:  r.Ledger,
+
+		RatingChanges: r.RatingChanges,
+	})
+	seatDraw := SeatDrawNone
+	if shuffle {
+		seatDraw = SeatDrawRandom
+	}
+	r.Scores = [4]int{}
+	r.Results = []mahjong.Result{}
+	r.Ledger = []LedgerEntry{}
+	r.RatingChanges = nil
+	r.Phase = PhaseLobby
+	return r.nextRound(seatDraw, t)
+}
+
+// recordRound records the outcome of the current round once it is over.
+func (r *Room) recordRound() {
+	r.Ledger = append(r.Ledger, ledgerEntries(len(r.Results), r.Round)...)
+	sortLedger(r.Ledger)
+	r.Results = append(r.Results, *r.Round.Result)
+	r.Scores = r.Round.Scores
+	if record := r.currentRoundRecord(); record != nil {
+		result := *r.Round.Result
+		record.Result = &result
+	}
+}
+
 func NewRoom(host Player) *Room {
 	room := &Room{
 		Phase:   PhaseLobby,
 		Players: []Player{host},
-		clients: make(map[chan RoomView]string),
+		Host:    host.ID,
+		Ruleset: RulesetDefault,
 		Results: []mahjong.Result{},
+		Ledger:  []LedgerEntry{},
+		Games:   []Game{},
+		Events:  []RoomEvent{},
+		Chat:    []ChatMessage{},
+
+		clients:      make(map[chan RoomView]string),
+		disconnected: make(map[string]time.Time),
 	}
 	return room
 }
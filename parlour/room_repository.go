@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgerrcode"
@@ -18,6 +20,17 @@ var errNotFound = errors.New("not found")
 type RoomRepository interface {
 	Save(room *Room) error
 	Get(id string) (*Room, error)
+
+	// ListOpen returns the public rooms which are waiting for players.
+	ListOpen(filter RoomFilter) ([]RoomListing, error)
+
+	// ListGames returns the games a player has played in, including games
+	// which are still in progress.
+	ListGames(playerID string) ([]Game, error)
+
+	// ListByPlayer returns the rooms a player has been in, most recently
+	// joined first.
+	ListByPlayer(playerID string, filter PlayerRoomFilter) ([]RoomSummary, error)
 }
 
 func newRoomID() string {
@@ -33,11 +46,16 @@ func newRoomID() string {
 type InMemoryRoomRepository struct {
 	sync.RWMutex
 	rooms map[string]*Room
+
+	// members maps player IDs to the time they joined each room they have
+	// been in.
+	members map[string]map[string]time.Time
 }
 
 func NewInMemoryRoomRepository() *InMemoryRoomRepository {
 	return &InMemoryRoomRepository{
-		rooms: map[string]*Room{},
+		rooms:   map[string]*Room{},
+		members: map[string]map[string]time.Time{},
 	}
 }
 
@@ -52,6 +70,14 @@ func (r *InMemoryRoomRepository) Save(room *Room) error {
 		room.ID = id
 	}
 	r.rooms[room.ID] = room
+	for _, playerID := range room.members() {
+		if r.members[playerID] == nil {
+			r.members[playerID] = make(map[string]time.Time)
+		}
+		if _, ok := r.members[playerID][room.ID]; !ok {
+			r.members[playerID][room.ID] = time.Now()
+		}
+	}
 	return nil
 }
 
@@ -66,8 +92,85 @@ func (r *InMemoryRoomRepository) Get(id string) (*Room, error) {
 	return room, nil
 }
 
+func (r *InMemoryRoomRepository) ListOpen(filter RoomFilter) ([]RoomListing, error) {
+	r.RLock()
+	rooms := make([]*Room, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		rooms = append(rooms, room)
+	}
+	r.RUnlock()
+	listings := []RoomListing{}
+	for _, room := range rooms {
+		room.WithRLock(func(room *Room) {
+			if room.open(filter) {
+				listings = append(listings, room.listing())
+			}
+		})
+	}
+	sort.Slice(listings, func(i, j int) bool {
+		return listings[i].ID < listings[j].ID
+	})
+	return listings, nil
+}
+
+func (r *InMemoryRoomRepository) ListGames(playerID string) ([]Game, error) {
+	r.RLock()
+	rooms := make([]*Room, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		rooms = append(rooms, room)
+	}
+	r.RUnlock()
+	var games []Game
+	for _, room := range rooms {
+		room.WithRLock(func(room *Room) {
+			for _, game := range room.allGames() {
+				if game.playedIn(playerID) {
+					games = append(games, game)
+				}
+			}
+		})
+	}
+	return games, nil
+}
+
+func (r *InMemoryRoomRepository) ListByPlayer(playerID string, filter PlayerRoomFilter) ([]RoomSummary, error) {
+	type membership struct {
+		room     *Room
+		joinedAt time.Time
+	}
+	r.RLock()
+	var memberships []membership
+	for roomID, joinedAt := range r.members[playerID] {
+		memberships = append(memberships, membership{room: r.rooms[roomID], joinedAt: joinedAt})
+	}
+	r.RUnlock()
+	sort.Slice(memberships, func(i, j int) bool {
+		if !memberships[i].joinedAt.Equal(memberships[j].joinedAt) {
+			return memberships[i].joinedAt.After(memberships[j].joinedAt)
+		}
+		return memberships[i].room.ID < memberships[j].room.ID
+	})
+	rooms := []RoomSummary{}
+	for _, m := range memberships {
+		m.room.WithRLock(func(room *Room) {
+			if filter.matches(room.Phase) {
+				rooms = append(rooms, room.summary(m.joinedAt))
+			}
+		})
+	}
+	if filter.Offset >= len(rooms) {
+		return []RoomSummary{}, nil
+	}
+	rooms = rooms[filter.Offset:]
+	if len(rooms) > filter.Limit {
+		rooms = rooms[:filter.Limit]
+	}
+	return rooms, nil
+}
+
 type Conn interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
@@ -85,14 +188,30 @@ func (p *PostgresRoomRepository) Save(room *Room) error {
 			if err != nil {
 				return fmt.Errorf("error inserting room: %w", err)
 			}
-			_, err = tx.Exec(ctx, `insert into rooms (id, nonce, phase, players, round, results)
-values ($1, $2, $3, $4, $5, $6)`,
+			_, err = tx.Exec(ctx, `insert into rooms (id, nonce, phase, players, round, results, scores, ledger, stakes, games, events, host, locked, chat, muted, public, ruleset, winds, passcode, rating_changes, rounds, tournament)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)`,
 				id,
 				room.Nonce,
 				room.Phase,
 				room.Players,
 				room.Round,
 				room.Results,
+				room.Scores,
+				room.Ledger,
+				room.Stakes,
+				room.Games,
+				room.Events,
+				room.Host,
+				room.Locked,
+				room.Chat,
+				room.Muted,
+				room.Public,
+				room.Ruleset,
+				room.Winds,
+				room.Passcode,
+				room.RatingChanges,
+				room.Rounds,
+				room.Tournament,
 			)
 			if err != nil {
 				var pgError *pgconn.PgError
@@ -104,31 +223,124 @@ values ($1, $2, $3, $4, $5, $6)`,
 				}
 				return fmt.Errorf("error inserting room: %w", err)
 			}
+			err = saveMembers(ctx, tx, id, room)
+			if err != nil {
+				_ = tx.Rollback(ctx)
+				return err
+			}
 			err = tx.Commit(ctx)
 			if err != nil {
 				return fmt.Errorf("error inserting room: %w", err)
 			}
 			room.ID = id
+			room.savedMembers = room.members()
 			return nil
 		}
 	}
-	_, err := p.conn.Exec(ctx, `insert into rooms (id, nonce, phase, players, round, results)
-values ($1, $2, $3, $4, $5, $6)
+	members := room.members()
+	changed := !sameMembers(members, room.savedMembers)
+	var db execer = p.conn
+	var tx pgx.Tx
+	if changed {
+		var err error
+		tx, err = p.conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("error saving room: %w", err)
+		}
+		defer tx.Rollback(ctx)
+		db = tx
+	}
+	_, err := db.Exec(ctx, `insert into rooms (id, nonce, phase, players, round, results, scores, ledger, stakes, games, events, host, locked, chat, muted, public, ruleset, winds, passcode, rating_changes, rounds, tournament)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
 on conflict (id) do update set nonce=excluded.nonce,
                                phase=excluded.phase,
                                players=excluded.players,
                                round=excluded.round,
-                               results=excluded.results`,
+                               results=excluded.results,
+                               scores=excluded.scores,
+                               ledger=excluded.ledger,
+                               stakes=excluded.stakes,
+                               games=excluded.games,
+                               events=excluded.events,
+                               host=excluded.host,
+                               locked=excluded.locked,
+                               chat=excluded.chat,
+                               muted=excluded.muted,
+                               public=excluded.public,
+                               ruleset=excluded.ruleset,
+                               winds=excluded.winds,
+                               passcode=excluded.passcode,
+                               rating_changes=excluded.rating_changes,
+                               rounds=excluded.rounds,
+                               tournament=excluded.tournament`,
 		room.ID,
 		room.Nonce,
 		room.Phase,
 		room.Players,
 		room.Round,
 		room.Results,
+		room.Scores,
+		room.Ledger,
+		room.Stakes,
+		room.Games,
+		room.Events,
+		room.Host,
+		room.Locked,
+		room.Chat,
+		room.Muted,
+		room.Public,
+		room.Ruleset,
+		room.Winds,
+		room.Passcode,
+		room.RatingChanges,
+		room.Rounds,
+		room.Tournament,
 	)
 	if err != nil {
 		return fmt.Errorf("error saving room: %w", err)
 	}
+	if changed {
+		err = saveMembers(ctx, tx, room.ID, room)
+		if err != nil {
+			return err
+		}
+		err = tx.Commit(ctx)
+		if err != nil {
+			return fmt.Errorf("error saving room: %w", err)
+		}
+	}
+	room.savedMembers = members
+	return nil
+}
+
+// sameMembers returns whether two lists of room members are the same.
+func sameMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+// saveMembers adds the players in a room to the index of the rooms each
+// player has been in.
+func saveMembers(ctx context.Context, db execer, roomID string, room *Room) error {
+	_, err := db.Exec(ctx, `insert into room_players (room_id, player_id, joined_at)
+select $1, unnest($2::text[]), now()
+on conflict do nothing`,
+		roomID, room.members(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving room players: %w", err)
+	}
 	return nil
 }
 
@@ -136,8 +348,8 @@ func (p *PostgresRoomRepository) Get(id string) (*Room, error) {
 	var room Room
 	err := p.conn.QueryRow(
 		context.Background(),
-		"select id, nonce, phase, players, round, results from rooms where id = $1", id,
-	).Scan(&room.ID, &room.Nonce, &room.Phase, &room.Players, &room.Round, &room.Results)
+		"select id, nonce, phase, players, round, results, coalesce(scores, '[0, 0, 0, 0]'), ledger, coalesce(stakes, '{}'), games, events, coalesce(host, players->0->>'id', ''), coalesce(locked, false), chat, muted, public, coalesce(ruleset, ''), winds, coalesce(passcode, ''), rating_changes, rounds, coalesce(tournament, '') from rooms where id = $1", id,
+	).Scan(&room.ID, &room.Nonce, &room.Phase, &room.Players, &room.Round, &room.Results, &room.Scores, &room.Ledger, &room.Stakes, &room.Games, &room.Events, &room.Host, &room.Locked, &room.Chat, &room.Muted, &room.Public, &room.Ruleset, &room.Winds, &room.Passcode, &room.RatingChanges, &room.Rounds, &room.Tournament)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, errNotFound
 	}
@@ -145,9 +357,108 @@ func (p *PostgresRoomRepository) Get(id string) (*Room, error) {
 		return nil, fmt.Errorf("error getting room: %w", err)
 	}
 	room.clients = make(map[chan RoomView]string)
+	room.disconnected = make(map[string]time.Time)
+	room.savedMembers = room.members()
 	return &room, nil
 }
 
+func (p *PostgresRoomRepository) ListOpen(filter RoomFilter) ([]RoomListing, error) {
+	rows, err := p.conn.Query(
+		context.Background(),
+		`select id, players, coalesce(host, players->0->>'id', ''), coalesce(ruleset, ''), coalesce(stakes, '{}'), winds, coalesce(passcode, '')
+from rooms
+where phase = $1
+  and public
+  and not coalesce(locked, false)
+  and jsonb_array_length(players) < 4
+  and ($2 = '' or coalesce(nullif(ruleset, ''), $3) = $2)
+order by id
+limit 100`,
+		PhaseLobby, filter.Ruleset, RulesetDefault,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error listing rooms: %w", err)
+	}
+	defer rows.Close()
+	listings := []RoomListing{}
+	for rows.Next() {
+		var room Room
+		err := rows.Scan(&room.ID, &room.Players, &room.Host, &room.Ruleset, &room.Stakes, &room.Winds, &room.Passcode)
+		if err != nil {
+			return nil, fmt.Errorf("error listing rooms: %w", err)
+		}
+		listings = append(listings, room.listing())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing rooms: %w", err)
+	}
+	return listings, nil
+}
+
+func (p *PostgresRoomRepository) ListGames(playerID string) ([]Game, error) {
+	rows, err := p.conn.Query(context.Background(), `select r.players, r.results, coalesce(r.scores, '[0, 0, 0, 0]'), r.ledger, r.games
+from room_players m
+join rooms r on r.id = m.room_id
+where m.player_id = $1`,
+		playerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error listing games: %w", err)
+	}
+	defer rows.Close()
+	var games []Game
+	for rows.Next() {
+		var room Room
+		err := rows.Scan(&room.Players, &room.Results, &room.Scores, &room.Ledger, &room.Games)
+		if err != nil {
+			return nil, fmt.Errorf("error listing games: %w", err)
+		}
+		for _, game := range room.allGames() {
+			if game.playedIn(playerID) {
+				games = append(games, game)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing games: %w", err)
+	}
+	return games, nil
+}
+
+func (p *PostgresRoomRepository) ListByPlayer(playerID string, filter PlayerRoomFilter) ([]RoomSummary, error) {
+	phases := make([]int, len(filter.Phases))
+	for i, phase := range filter.Phases {
+		phases[i] = int(phase)
+	}
+	rows, err := p.conn.Query(context.Background(), `select r.id, r.phase, r.players, coalesce(r.host, r.players->0->>'id', ''), coalesce(r.ruleset, ''), coalesce(r.scores, '[0, 0, 0, 0]'), m.joined_at
+from room_players m
+         join rooms r on r.id = m.room_id
+where m.player_id = $1
+  and (cardinality($2::int[]) = 0 or r.phase = any ($2::int[]))
+order by m.joined_at desc, r.id
+limit $3 offset $4`,
+		playerID, phases, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error listing player rooms: %w", err)
+	}
+	defer rows.Close()
+	rooms := []RoomSummary{}
+	for rows.Next() {
+		var room Room
+		var joinedAt time.Time
+		err := rows.Scan(&room.ID, &room.Phase, &room.Players, &room.Host, &room.Ruleset, &room.Scores, &joinedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error listing player rooms: %w", err)
+		}
+		rooms = append(rooms, room.summary(joinedAt))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing player rooms: %w", err)
+	}
+	return rooms, nil
+}
+
 func NewPostgresRoomRepository(conn Conn) *PostgresRoomRepository {
 	return &PostgresRoomRepository{
 		conn: conn,
@@ -3,6 +3,7 @@ package parlour
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
@@ -46,7 +47,14 @@ func TestPostgresRoomRepository(t *testing.T) {
 					WinningTiles: []mahjong.Tile{mahjong.TileDragonsWhite},
 				},
 			},
-			clients: map[chan RoomView]string{},
+			Scores: [4]int{-2, 6, -2, -2},
+			Host:   "iPRk13H8j/MHaP3vhCjnAg",
+			Locked: true,
+			Ledger: []LedgerEntry{
+				{Round: 0, Reason: LedgerWin, Deltas: [4]int{-2, 6, -2, -2}},
+			},
+			clients:      map[chan RoomView]string{},
+			disconnected: map[string]time.Time{},
 		}
 		err := repo.Save(room)
 		assert.NoError(t, err)
@@ -85,3 +93,110 @@ func TestPostgresRoomRepository(t *testing.T) {
 		assert.NotEmpty(t, room.ID)
 	})
 }
+
+func TestPostgresRatingRepository_Update(t *testing.T) {
+	tx := getTx()
+	defer tx.Rollback(context.Background())
+
+	repo := NewPostgresRatingRepository(tx)
+	assert.NoError(t, repo.Update(RulesetDefault, []RatingChange{{Username: "alice", Delta: 10}, {}}))
+	assert.NoError(t, repo.Update(RulesetDefault, []RatingChange{{Username: "alice", Delta: -3}}))
+	ratings, err := repo.Get(RulesetDefault, []string{"alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Rating{
+		"alice": {Username: "alice", Ruleset: RulesetDefault, Rating: InitialRating + 7, Games: 2},
+	}, ratings)
+}
+
+func TestPostgresRoomRepository_ListOpen(t *testing.T) {
+	tx := getTx()
+	defer tx.Rollback(context.Background())
+
+	repo := NewPostgresRoomRepository(tx)
+	open := NewRoom(Player{ID: "alice", Name: "Alice"})
+	open.Public = true
+	open.Ruleset = RulesetShooter
+	private := NewRoom(Player{ID: "bob", Name: "Bob"})
+	assert.NoError(t, repo.Save(open))
+	assert.NoError(t, repo.Save(private))
+
+	listings, err := repo.ListOpen(RoomFilter{})
+	assert.NoError(t, err)
+	assert.Contains(t, listings, RoomListing{ID: open.ID, Host: "Alice", Players: 1, Ruleset: RulesetShooter, Winds: 4})
+	for _, listing := range listings {
+		assert.NotEqual(t, private.ID, listing.ID)
+	}
+
+	listings, err = repo.ListOpen(RoomFilter{Ruleset: RulesetDefault})
+	assert.NoError(t, err)
+	for _, listing := range listings {
+		assert.NotEqual(t, open.ID, listing.ID)
+	}
+}
+
+func TestInMemoryRoomRepository_ListOpen(t *testing.T) {
+	repo := NewInMemoryRoomRepository()
+	open := NewRoom(Player{ID: "alice", Name: "Alice"})
+	open.Public = true
+	shooter := NewRoom(Player{ID: "bob", Name: "Bob"})
+	shooter.Public = true
+	shooter.Ruleset = RulesetShooter
+	private := NewRoom(Player{ID: "carol", Name: "Carol"})
+	locked := NewRoom(Player{ID: "dave", Name: "Dave"})
+	locked.Public = true
+	locked.Locked = true
+	started := newFullRoom()
+	started.Public = true
+	started.Phase = PhaseInProgress
+	for _, room := range []*Room{open, shooter, private, locked, started} {
+		_ = repo.Save(room)
+	}
+
+	listings, err := repo.ListOpen(RoomFilter{Ruleset: RulesetShooter})
+	assert.NoError(t, err)
+	assert.Equal(t, []RoomListing{
+		{ID: shooter.ID, Host: "Bob", Players: 1, Ruleset: RulesetShooter, Winds: 4},
+	}, listings)
+
+	listings, err = repo.ListOpen(RoomFilter{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []RoomListing{
+		{ID: open.ID, Host: "Alice", Players: 1, Ruleset: RulesetDefault, Winds: 4},
+		{ID: shooter.ID, Host: "Bob", Players: 1, Ruleset: RulesetShooter, Winds: 4},
+	}, listings)
+}
+
+func TestPostgresRoomRepository_ListByPlayer(t *testing.T) {
+	tx := getTx()
+	defer tx.Rollback(context.Background())
+
+	repo := NewPostgresRoomRepository(tx)
+	lobby := NewRoom(Player{ID: "alice", Name: "Alice"})
+	finished := NewRoom(Player{ID: "bob", Name: "Bob"})
+	finished.Players = append(finished.Players, Player{ID: "alice", Name: "Alice"}, Player{ID: "bot1", Name: "Bot", IsBot: true})
+	finished.Phase = PhaseFinished
+	assert.NoError(t, repo.Save(lobby))
+	assert.NoError(t, repo.Save(finished))
+
+	rooms, err := repo.ListByPlayer("alice", PlayerRoomFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 2)
+
+	rooms, err = repo.ListByPlayer("alice", PlayerRoomFilter{Phases: []Phase{PhaseFinished}, Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, rooms, 1) {
+		assert.Equal(t, finished.ID, rooms[0].ID)
+		assert.Equal(t, "Bob", rooms[0].Host)
+		assert.Equal(t, []string{"Bob", "Alice", "Bot"}, rooms[0].Players)
+	}
+
+	rooms, err = repo.ListByPlayer("bot1", PlayerRoomFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+
+	lobby.Players = append(lobby.Players, Player{ID: "carol", Name: "Carol"})
+	assert.NoError(t, repo.Save(lobby))
+	rooms, err = repo.ListByPlayer("carol", PlayerRoomFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 1)
+}
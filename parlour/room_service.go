@@ -1,8 +1,11 @@
 package parlour
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Error struct {
@@ -17,6 +20,17 @@ func (e *Error) Unwrap() error {
 type roomService struct {
 	RoomRepository RoomRepository
 
+	// GracePeriod is how long a player who leaves during a game has to return
+	// before a bot takes over their seat.
+	GracePeriod time.Duration
+
+	chatLimiter       *rateLimiter
+	playerJoinLimiter *rateLimiter
+	roomJoinLimiter   *rateLimiter
+	inviter           inviter
+	ratingService     *ratingService
+	tournamentService *tournamentService
+
 	cache map[string]*Room
 	sync.Mutex
 }
@@ -35,19 +49,17 @@ func (s *roomService) Get(id string) (*Room, error) {
 	s.cache[room.ID] = room
 
 	// start bots
-	for _, player := range room.Players {
-		if player.IsBot {
-			bot := Bot{
-				ID:      player.ID,
-				Room:    room,
-				Updates: make(chan RoomView, 1),
-				AI:      discardRandomTileAI{},
+	room.WithLock(func(r *Room) {
+		for _, player := range r.Players {
+			if player.IsBot {
+				s.startBot(r, player.ID)
+			} else if player.Substitute != "" {
+				s.startBot(r, player.Substitute)
+			} else if player.Vacated {
+				s.scheduleTakeOver(r, player.ID)
 			}
-			room.clients[bot.Updates] = bot.ID
-			go bot.Start(s)
 		}
-	}
-	room.broadcast()
+	})
 
 	return room, nil
 }
@@ -67,9 +79,88 @@ func (s *roomService) Create(host Player) (*Room, error) {
 	return room, nil
 }
 
-func (s *roomService) AddPlayer(room *Room, player Player) error {
+// CreateTable creates a room for players, fills any empty seats with bots and
+// starts a game with randomly assigned seats. If tournamentID is set, the room
+// is a table in that tournament.
+func (s *roomService) CreateTable(players []Player, settings RoomSettings, tournamentID string) (*Room, error) {
+	room, err := s.SeatTable(players, settings, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.StartTable(room)
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// SeatTable creates a room for players and fills any empty seats with bots
+// without starting a game, so that the game can be started once every table
+// it is played alongside has been created.
+func (s *roomService) SeatTable(players []Player, settings RoomSettings, tournamentID string) (*Room, error) {
+	room, err := s.Create(players[0])
+	if err != nil {
+		return nil, err
+	}
+	err = s.update(room, func(r *Room) error {
+		for _, player := range players[1:] {
+			player.Name = r.uniqueName(player.Name)
+			r.Players = append(r.Players, player)
+		}
+		for len(r.Players) < 4 {
+			name := r.nextBotName()
+			r.Players = append(r.Players, Player{
+				ID:    name,
+				Name:  name,
+				IsBot: true,
+			})
+		}
+		r.Ruleset = settings.Ruleset
+		r.Winds = settings.Winds
+		r.Tournament = tournamentID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// StartTable starts a game with randomly assigned seats in a room created by
+// SeatTable. Rooms whose game has already started are left as they are.
+func (s *roomService) StartTable(room *Room) error {
+	return s.update(room, func(r *Room) error {
+		if r.Phase != PhaseLobby {
+			return nil
+		}
+		err := r.nextRound(SeatDrawRandom, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, player := range r.Players {
+			if player.IsBot && !r.connected(player.ID) {
+				s.startBot(r, player.ID)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *roomService) AddPlayer(room *Room, player Player, credentials JoinCredentials) error {
 	var svcErr error
 	room.WithLock(func(r *Room) {
+		if r.seat(player.ID) == -1 && r.Passcode != "" {
+			now := time.Now()
+			if !s.playerJoinLimiter.allow(player.ID, now) || !s.roomJoinLimiter.allow(r.ID, now) {
+				svcErr = &Error{error: errTooManyJoins}
+				return
+			}
+			err := r.checkPasscode(s.inviter, credentials, now)
+			if err != nil {
+				svcErr = &Error{error: err}
+				return
+			}
+		}
 		err := room.addPlayer(player)
 		if err != nil {
 			svcErr = &Error{error: err}
@@ -83,26 +174,152 @@ func (s *roomService) AddPlayer(room *Room, player Player) error {
 func (s *roomService) RemovePlayer(room *Room, playerID string) error {
 	var svcErr error
 	room.WithLock(func(r *Room) {
-		room.removePlayer(playerID)
+		if room.removePlayer(playerID, time.Now()) {
+			s.scheduleTakeOver(r, playerID)
+		}
 		svcErr = s.RoomRepository.Save(r)
 	})
 	return svcErr
 }
 
+// scheduleTakeOver has a bot take over the seat vacated by playerID once the
+// grace period is over, unless they return before then. Any take over already
+// scheduled for playerID is cancelled. The caller must hold the lock on the
+// room.
+func (s *roomService) scheduleTakeOver(room *Room, playerID string) {
+	room.cancelTakeOver(playerID)
+	if room.takeOvers == nil {
+		room.takeOvers = make(map[string]*time.Timer)
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(s.GracePeriod, func() {
+		room.WithLock(func(r *Room) {
+			if r.takeOvers[playerID] != timer {
+				// the player returned or left again in the meantime
+				return
+			}
+			delete(r.takeOvers, playerID)
+			name := r.nextBotName()
+			if name == "" || !r.takeOverSeat(playerID, name, time.Now()) {
+				return
+			}
+			s.startBot(r, name)
+			err := s.RoomRepository.Save(r)
+			if err != nil {
+				fmt.Printf("room=%s error saving room: %v\n", r.ID, err)
+			}
+		})
+	})
+	room.takeOvers[playerID] = timer
+}
+
 func (s *roomService) Dispatch(room *Room, playerID string, action Action) error {
 	var svcErr error
+	var finished bool
 	room.WithLock(func(r *Room) {
+		phase := r.Phase
 		err := room.reduce(playerID, action)
 		if err != nil {
 			svcErr = &Error{error: err}
 			return
 		}
 		svcErr = s.RoomRepository.Save(r)
+		finished = svcErr == nil && phase != PhaseFinished && r.Phase == PhaseFinished
 	})
+	if finished {
+		s.recordTournamentTable(room)
+		s.rate(room)
+	}
 	return svcErr
 }
 
-var botNames = []string{"Francisco Bot", "Lupe Bot", "Mordecai Bot"}
+// recordTournamentTable records the final scores of a room whose game just
+// finished in its tournament. Tables which fail to be recorded here are
+// recorded the next time the tournament is viewed.
+func (s *roomService) recordTournamentTable(room *Room) {
+	if room.Tournament == "" || s.tournamentService == nil {
+		return
+	}
+	err := s.tournamentService.RecordTable(room)
+	if err != nil {
+		fmt.Printf("room=%s tournament=%s error recording table: %v\n", room.ID, room.Tournament, err)
+	}
+}
+
+// rate updates the ratings of the players in a room whose game just finished.
+// The ratings are looked up without holding the lock on the room, and the
+// rating changes are saved with the room before they are added to the
+// ratings themselves, so ratings are never updated for a game whose rating
+// changes were not saved.
+func (s *roomService) rate(room *Room) {
+	var rated bool
+	var ruleset Ruleset
+	var game Game
+	var index int
+	room.WithRLock(func(r *Room) {
+		rated = r.Phase == PhaseFinished && r.rated()
+		ruleset = r.ruleset()
+		game = Game{Players: append([]Player(nil), r.Players...), Scores: r.Scores}
+		index = len(r.Games)
+	})
+	if !rated {
+		return
+	}
+	changes, err := s.ratingService.Changes(ruleset, game)
+	if err != nil {
+		fmt.Printf("room=%s error updating ratings: %v\n", room.ID, err)
+		return
+	}
+	room.WithLock(func(r *Room) {
+		if index < len(r.Games) {
+			// a rematch started in the meantime
+			r.Games[index].RatingChanges = changes
+		} else {
+			r.RatingChanges = changes
+		}
+		err = s.RoomRepository.Save(r)
+		if err == nil {
+			r.broadcast()
+		}
+	})
+	if err != nil {
+		fmt.Printf("room=%s error saving rating changes: %v\n", room.ID, err)
+		return
+	}
+	err = s.ratingService.RatingRepository.Update(ruleset, changes)
+	if err != nil {
+		fmt.Printf("room=%s error updating ratings: %v\n", room.ID, err)
+	}
+}
+
+func (s *roomService) SetStakes(room *Room, playerID string, stakes Stakes) error {
+	var svcErr error
+	room.WithLock(func(r *Room) {
+		err := r.setStakes(playerID, stakes)
+		if err != nil {
+			svcErr = &Error{error: err}
+			return
+		}
+		svcErr = s.RoomRepository.Save(r)
+	})
+	return svcErr
+}
+
+var botNames = []string{"Francisco Bot", "Lupe Bot", "Mordecai Bot", "Ozzie Bot"}
+
+// startBot subscribes a bot to the room and starts it. The caller must hold
+// the lock on the room.
+func (s *roomService) startBot(r *Room, id string) {
+	bot := Bot{
+		ID:      id,
+		Room:    r,
+		Updates: make(chan RoomView, 1),
+		AI:      discardRandomTileAI{},
+	}
+	r.clients[bot.Updates] = bot.ID
+	sendView(bot.Updates, r.view(bot.ID))
+	go bot.Start(s)
+}
 
 func (s *roomService) AddBot(room *Room, playerID string) error {
 	var svcErr error
@@ -115,29 +332,145 @@ func (s *roomService) AddBot(room *Room, playerID string) error {
 			svcErr = &Error{error: errRoomFull}
 			return
 		}
-		name := botNames[len(r.Players)-1]
+		name := r.nextBotName()
 		r.Players = append(r.Players, Player{
 			ID:    name,
 			Name:  name,
 			IsBot: true,
 		})
 		r.broadcast()
-		bot := Bot{
-			ID:      name,
-			Room:    r,
-			Updates: make(chan RoomView),
-			AI:      discardRandomTileAI{},
-		}
-		r.clients[bot.Updates] = bot.ID
-		go bot.Start(s)
+		s.startBot(r, name)
+		svcErr = s.RoomRepository.Save(r)
+	})
+	return svcErr
+}
+
+// update applies f to a room while holding its lock, then saves the room if f
+// succeeded.
+func (s *roomService) update(room *Room, f func(r *Room) error) error {
+	var svcErr error
+	room.WithLock(func(r *Room) {
+		err := f(r)
+		if err != nil {
+			svcErr = &Error{error: err}
+			return
+		}
 		svcErr = s.RoomRepository.Save(r)
 	})
 	return svcErr
 }
 
+func (s *roomService) KickPlayer(room *Room, playerID string, seat int) error {
+	return s.update(room, func(r *Room) error {
+		return r.kickPlayer(playerID, seat, time.Now())
+	})
+}
+
+func (s *roomService) TransferHost(room *Room, playerID string, seat int) error {
+	return s.update(room, func(r *Room) error {
+		return r.transferHost(playerID, seat, time.Now())
+	})
+}
+
+func (s *roomService) SetLocked(room *Room, playerID string, locked bool) error {
+	return s.update(room, func(r *Room) error {
+		return r.setLocked(playerID, locked, time.Now())
+	})
+}
+
+func (s *roomService) VoidRound(room *Room, playerID string) error {
+	return s.update(room, func(r *Room) error {
+		return r.voidRound(playerID, time.Now())
+	})
+}
+
+func (s *roomService) AdjustScores(room *Room, playerID string, adjustment Adjustment) error {
+	return s.update(room, func(r *Room) error {
+		return r.adjustScores(playerID, adjustment, time.Now())
+	})
+}
+
+func (s *roomService) ReplaceWithBot(room *Room, playerID string, seat int) error {
+	return s.update(room, func(r *Room) error {
+		name := r.nextBotName()
+		if name == "" {
+			return errors.New("no bots available")
+		}
+		err := r.replaceWithBot(playerID, seat, Player{
+			ID:    name,
+			Name:  name,
+			IsBot: true,
+		}, time.Now())
+		if err != nil {
+			return err
+		}
+		s.startBot(r, name)
+		return nil
+	})
+}
+
+func (s *roomService) PostChat(room *Room, playerID string, message ChatMessage) error {
+	t := time.Now()
+	return s.update(room, func(r *Room) error {
+		// only messages which would be posted count towards the limit
+		if err := r.checkChat(playerID, message); err != nil {
+			return err
+		}
+		if !s.chatLimiter.allow(playerID, t) {
+			return errRateLimited
+		}
+		return r.postChat(playerID, message, t)
+	})
+}
+
+func (s *roomService) SetMuted(room *Room, playerID string, seat int, muted bool) error {
+	return s.update(room, func(r *Room) error {
+		return r.setMuted(playerID, seat, muted, time.Now())
+	})
+}
+
+func (s *roomService) ClearChat(room *Room, playerID string) error {
+	return s.update(room, func(r *Room) error {
+		return r.clearChat(playerID, time.Now())
+	})
+}
+
+func (s *roomService) UpdateSettings(room *Room, playerID string, settings RoomSettings) error {
+	return s.update(room, func(r *Room) error {
+		return r.updateSettings(playerID, settings, time.Now())
+	})
+}
+
+func (s *roomService) Invite(room *Room, playerID string, duration time.Duration) (string, error) {
+	var invite string
+	var svcErr error
+	room.WithRLock(func(r *Room) {
+		var err error
+		invite, err = r.invite(s.inviter, playerID, duration, time.Now())
+		if err != nil {
+			svcErr = &Error{error: err}
+		}
+	})
+	return invite, svcErr
+}
+
+func (s *roomService) ListOpen(filter RoomFilter) ([]RoomListing, error) {
+	listings, err := s.RoomRepository.ListOpen(filter)
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	return listings, nil
+}
+
 func newRoomService(roomRepository RoomRepository) *roomService {
 	return &roomService{
-		RoomRepository: roomRepository,
-		cache:          make(map[string]*Room),
+		RoomRepository:    roomRepository,
+		GracePeriod:       DefaultGracePeriod,
+		chatLimiter:       newRateLimiter(5, 10*time.Second),
+		playerJoinLimiter: newRateLimiter(PlayerJoinAttempts, JoinWindow),
+		roomJoinLimiter:   newRateLimiter(RoomJoinAttempts, JoinWindow),
+		inviter:           inviter{key: newInviteKey()},
+		ratingService:     newRatingService(NewInMemoryRatingRepository()),
+		cache:             make(map[string]*Room),
 	}
 }
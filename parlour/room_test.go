@@ -2,8 +2,10 @@ package parlour
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yi-jiayu/mahjong.go"
 )
 
 func TestRoom_AddPlayer(t *testing.T) {
@@ -45,3 +47,63 @@ func TestRoom_reduce(t *testing.T) {
 		assert.EqualError(t, err, "invalid action")
 	})
 }
+
+func TestRoom_rematch(t *testing.T) {
+	newFinishedRoom := func() *Room {
+		r := newFullRoom()
+		r.Phase = PhaseFinished
+		r.Scores = [4]int{8, -8, 0, 0}
+		r.Ledger = []LedgerEntry{{Round: 0, Reason: LedgerWin, Deltas: [4]int{8, -8, 0, 0}}}
+		return r
+	}
+	t.Run("game not finished", func(t *testing.T) {
+		r := newFinishedRoom()
+		r.Phase = PhaseLobby
+		err := r.reduce("id1", Action{Type: ActionRematch})
+		assert.EqualError(t, err, "game not finished")
+		assert.Empty(t, r.Games)
+	})
+	t.Run("tournament", func(t *testing.T) {
+		r := newFinishedRoom()
+		r.Tournament = "T1"
+		err := r.reduce("id1", Action{Type: ActionRematch})
+		assert.Equal(t, errTournamentRoom, err)
+		assert.Empty(t, r.Games)
+	})
+	t.Run("not enough players", func(t *testing.T) {
+		r := newFinishedRoom()
+		r.removePlayer("id4", time.Now())
+		err := r.reduce("id1", Action{Type: ActionRematch})
+		assert.EqualError(t, err, "not enough players")
+		assert.Equal(t, PhaseFinished, r.Phase)
+		assert.Empty(t, r.Games)
+		assert.Equal(t, [4]int{8, -8, 0, 0}, r.Scores)
+		assert.Len(t, r.Ledger, 1)
+	})
+	t.Run("success", func(t *testing.T) {
+		r := newFinishedRoom()
+		players := append([]Player{}, r.Players...)
+		err := r.reduce("id1", Action{Type: ActionRematch})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, r.Nonce)
+		assert.Equal(t, PhaseInProgress, r.Phase)
+		assert.NotNil(t, r.Round)
+		assert.Equal(t, players, r.Players)
+		assert.Equal(t, [4]int{}, r.Scores)
+		assert.Empty(t, r.Results)
+		assert.Empty(t, r.Ledger)
+		assert.Equal(t, []Game{{
+			Players: players,
+			Scores:  [4]int{8, -8, 0, 0},
+			Results: []mahjong.Result{},
+			Ledger:  []LedgerEntry{{Round: 0, Reason: LedgerWin, Deltas: [4]int{8, -8, 0, 0}}},
+		}}, r.Games)
+	})
+	t.Run("shuffle seats", func(t *testing.T) {
+		r := newFinishedRoom()
+		err := r.reduce("id1", Action{Type: ActionRematch, Shuffle: true})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, r.Games[0].Players, r.Players)
+		assert.Equal(t, SeatDrawRandom, r.Events[0].SeatDraw)
+	})
+}
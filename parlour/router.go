@@ -1,14 +1,15 @@
 package parlour
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
-	mathRand "math/rand"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -20,7 +21,10 @@ import (
 const (
 	KeySessionName = "session"
 	KeyPlayerID    = "playerID"
+	KeyUsername    = "username"
+	KeyAPIToken    = "apiToken"
 	KeyRoom        = "room"
+	KeyTournament  = "tournament"
 )
 
 var sessionOptions = sessions.Options{
@@ -29,10 +33,11 @@ var sessionOptions = sessions.Options{
 	MaxAge:   2592000, // 30 days in seconds
 }
 
-// newPlayerID returns opaque string containing n bytes of entropy.
+// newPlayerID returns opaque string containing n bytes of entropy. Player IDs
+// become account IDs when guests register, so they must be unpredictable.
 func newPlayerID(n int) string {
 	data := make([]byte, n)
-	_, err := mathRand.Read(data)
+	_, err := rand.Read(data)
 	if err != nil {
 		panic(err)
 	}
@@ -40,6 +45,11 @@ func newPlayerID(n int) string {
 }
 
 func setPlayerID(c *gin.Context) {
+	if c.GetString(KeyPlayerID) != "" {
+		// already authenticated with an API token
+		c.Next()
+		return
+	}
 	session := sessions.Default(c)
 	session.Options(sessionOptions)
 	var playerID string
@@ -53,6 +63,9 @@ func setPlayerID(c *gin.Context) {
 	}
 	c.Set(KeyPlayerID, playerID)
 	session.Set(KeyPlayerID, playerID)
+	if username, ok := session.Get(KeyUsername).(string); ok {
+		c.Set(KeyUsername, username)
+	}
 	err := session.Save()
 	if err != nil {
 		fmt.Printf("error saving session: %v\n", err)
@@ -60,6 +73,175 @@ func setPlayerID(c *gin.Context) {
 	c.Next()
 }
 
+// signIn binds the session to an account.
+func signIn(c *gin.Context, account *Account) error {
+	session := sessions.Default(c)
+	session.Set(KeyPlayerID, account.ID)
+	session.Set(KeyUsername, account.Username)
+	c.Set(KeyPlayerID, account.ID)
+	c.Set(KeyUsername, account.Username)
+	return session.Save()
+}
+
+// registerHandler creates an account for the current player and signs them in.
+// Accounts can only be registered, signed in to and signed out of with a
+// session, so that a token cannot be exchanged for a session cookie.
+func (p *Parlour) registerHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(KeyAPIToken) {
+			_ = c.Error(&Error{error: errTokenNotAllowed})
+			return
+		}
+		if c.GetString(KeyUsername) != "" {
+			_ = c.Error(errAlreadySignedIn)
+			return
+		}
+		playerID := c.GetString(KeyPlayerID)
+		account, err := p.accountService.Register(playerID, c.PostForm("username"), c.PostForm("password"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = signIn(c, account)
+		if err != nil {
+			_ = c.Error(&Error{error: err, internal: true})
+			return
+		}
+		c.Status(http.StatusCreated)
+	}
+}
+
+func (p *Parlour) signInHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(KeyAPIToken) {
+			_ = c.Error(&Error{error: errTokenNotAllowed})
+			return
+		}
+		account, err := p.accountService.Authenticate(c.PostForm("username"), c.PostForm("password"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = signIn(c, account)
+		if err != nil {
+			_ = c.Error(&Error{error: err, internal: true})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// signOutHandler unbinds the session from an account and gives it a new guest
+// player ID.
+func signOutHandler(c *gin.Context) {
+	if c.GetBool(KeyAPIToken) {
+		_ = c.Error(&Error{error: errTokenNotAllowed})
+		return
+	}
+	session := sessions.Default(c)
+	session.Delete(KeyUsername)
+	session.Set(KeyPlayerID, newPlayerID(16))
+	err := session.Save()
+	if err != nil {
+		_ = c.Error(&Error{error: err, internal: true})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// createTokenHandler issues a token for the current player. Tokens can only be
+// created and revoked with a session, so that a leaked token cannot be used to
+// issue more tokens or to revoke the player's other tokens.
+func (p *Parlour) createTokenHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(KeyAPIToken) {
+			_ = c.Error(&Error{error: errTokenNotAllowed})
+			return
+		}
+		playerID := c.GetString(KeyPlayerID)
+		apiToken, token, err := p.tokenService.Create(playerID, c.PostForm("name"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"id":    apiToken.ID,
+			"name":  apiToken.Name,
+			"token": token,
+		})
+	}
+}
+
+func (p *Parlour) listTokensHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		tokens, err := p.tokenService.List(playerID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+func (p *Parlour) revokeTokenHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(KeyAPIToken) {
+			_ = c.Error(&Error{error: errTokenNotAllowed})
+			return
+		}
+		playerID := c.GetString(KeyPlayerID)
+		err := p.tokenService.Revoke(playerID, c.Param("tokenID"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func meHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"player_id": c.GetString(KeyPlayerID),
+		"username":  c.GetString(KeyUsername),
+	})
+}
+
+func (p *Parlour) myRoomsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter PlayerRoomFilter
+		for _, name := range c.QueryArray("phase") {
+			phase, err := parsePhase(name)
+			if err != nil {
+				_ = c.Error(err)
+				return
+			}
+			filter.Phases = append(filter.Phases, phase)
+		}
+		var err error
+		if c.Query("limit") != "" {
+			filter.Limit, err = strconv.Atoi(c.Query("limit"))
+			if err != nil {
+				_ = c.Error(errors.New("invalid limit"))
+				return
+			}
+		}
+		if c.Query("offset") != "" {
+			filter.Offset, err = strconv.Atoi(c.Query("offset"))
+			if err != nil {
+				_ = c.Error(errors.New("invalid offset"))
+				return
+			}
+		}
+		page, err := p.roomService.ListPlayerRooms(c.GetString(KeyPlayerID), filter)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
 func handleErrors(c *gin.Context) {
 	c.Next()
 	err := c.Errors.Last()
@@ -98,8 +280,9 @@ func (p *Parlour) createRoomHandler() gin.HandlerFunc {
 			return
 		}
 		player := Player{
-			ID:   playerID,
-			Name: name,
+			ID:       playerID,
+			Name:     name,
+			Username: c.GetString(KeyUsername),
 		}
 		room, err := p.roomService.Create(player)
 		if err != nil {
@@ -111,6 +294,51 @@ func (p *Parlour) createRoomHandler() gin.HandlerFunc {
 	}
 }
 
+func (p *Parlour) listRoomsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := RoomFilter{
+			Ruleset: Ruleset(c.Query("ruleset")),
+		}
+		listings, err := p.roomService.ListOpen(filter)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, listings)
+	}
+}
+
+func (p *Parlour) leaderboardHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var limit int
+		if c.Query("limit") != "" {
+			var err error
+			limit, err = strconv.Atoi(c.Query("limit"))
+			if err != nil {
+				_ = c.Error(errors.New("invalid limit"))
+				return
+			}
+		}
+		ratings, err := p.roomService.ratingService.Leaderboard(Ruleset(c.Query("ruleset")), limit)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, ratings)
+	}
+}
+
+func (p *Parlour) playerStatsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := p.statsService.Stats(c.Param("playerID"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
 func (p *Parlour) joinRoomHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		playerID := c.GetString(KeyPlayerID)
@@ -121,10 +349,15 @@ func (p *Parlour) joinRoomHandler() gin.HandlerFunc {
 			return
 		}
 		player := Player{
-			ID:   playerID,
-			Name: name,
+			ID:       playerID,
+			Name:     name,
+			Username: c.GetString(KeyUsername),
+		}
+		credentials := JoinCredentials{
+			Passcode: c.PostForm("passcode"),
+			Invite:   c.PostForm("invite"),
 		}
-		err = p.roomService.AddPlayer(room, player)
+		err = p.roomService.AddPlayer(room, player, credentials)
 		if err != nil {
 			_ = c.Error(err)
 			return
@@ -133,6 +366,28 @@ func (p *Parlour) joinRoomHandler() gin.HandlerFunc {
 	}
 }
 
+func (p *Parlour) createInviteHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		var duration time.Duration
+		if v := c.PostForm("duration"); v != "" {
+			var err error
+			duration, err = time.ParseDuration(v)
+			if err != nil {
+				_ = c.Error(errors.New("invalid invite duration"))
+				return
+			}
+		}
+		invite, err := p.roomService.Invite(room, playerID, duration)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.String(http.StatusCreated, invite)
+	}
+}
+
 func (p *Parlour) leaveRoomHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		playerID := c.GetString(KeyPlayerID)
@@ -145,6 +400,30 @@ func (p *Parlour) leaveRoomHandler() gin.HandlerFunc {
 	}
 }
 
+func exportRecordHandler(c *gin.Context) {
+	room := c.MustGet(KeyRoom).(*Room)
+	var record GameRecord
+	room.WithRLock(func(r *Room) {
+		record = r.exportRecord()
+	})
+	c.JSON(http.StatusOK, record)
+}
+
+func exportTranscriptHandler(c *gin.Context) {
+	room := c.MustGet(KeyRoom).(*Room)
+	var record GameRecord
+	room.WithRLock(func(r *Room) {
+		record = r.exportRecord()
+	})
+	var b strings.Builder
+	err := writeTranscript(&b, record)
+	if err != nil {
+		_ = c.Error(&Error{error: err, internal: true})
+		return
+	}
+	c.String(http.StatusOK, b.String())
+}
+
 func (p *Parlour) subscribeRoomHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		playerID := c.GetString(KeyPlayerID)
@@ -188,6 +467,204 @@ func (p *Parlour) roomActionsHandler() gin.HandlerFunc {
 	}
 }
 
+func (p *Parlour) setStakesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		var stakes Stakes
+		err := c.ShouldBindJSON(&stakes)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.SetStakes(room, playerID, stakes)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func getSeat(c *gin.Context) (int, error) {
+	seat, err := strconv.Atoi(c.Param("seat"))
+	if err != nil {
+		return 0, errInvalidSeat
+	}
+	return seat, nil
+}
+
+func (p *Parlour) updateSettingsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		var settings RoomSettings
+		err := c.ShouldBindJSON(&settings)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.UpdateSettings(room, playerID, settings)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) kickPlayerHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		seat, err := getSeat(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.KickPlayer(room, playerID, seat)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) transferHostHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		var body struct {
+			Seat int `json:"seat"`
+		}
+		err := c.ShouldBindJSON(&body)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.TransferHost(room, playerID, body.Seat)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) setLockedHandler(locked bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		err := p.roomService.SetLocked(room, playerID, locked)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) adjustScoresHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		var adjustment Adjustment
+		err := c.ShouldBindJSON(&adjustment)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.AdjustScores(room, playerID, adjustment)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) voidRoundHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		err := p.roomService.VoidRound(room, playerID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) replaceWithBotHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		seat, err := getSeat(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.ReplaceWithBot(room, playerID, seat)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) postChatHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		var message ChatMessage
+		err := c.ShouldBindJSON(&message)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.PostChat(room, playerID, message)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) clearChatHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		err := p.roomService.ClearChat(room, playerID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) setMutedHandler(muted bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		room := c.MustGet(KeyRoom).(*Room)
+		seat, err := getSeat(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.roomService.SetMuted(room, playerID, seat, muted)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
 func setConcealedHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		room := c.MustGet(KeyRoom).(*Room)
@@ -237,10 +714,13 @@ func reshuffleHandler() gin.HandlerFunc {
 			c.String(http.StatusBadRequest, "round not started")
 			return
 		}
+		if room.currentRoundRecord() != nil {
+			room.Rounds = room.Rounds[:len(room.Rounds)-1]
+		}
 		room.Round = &mahjong.Round{
-			Rules: mahjong.RulesDefault,
+			Rules: room.ruleset().rules(),
 		}
-		room.Round.Start(mathRand.Int63(), time.Now())
+		room.startRound(time.Now())
 		room.broadcast()
 	}
 }
@@ -257,6 +737,192 @@ func (p *Parlour) addBotHandler() gin.HandlerFunc {
 	}
 }
 
+func (p *Parlour) joinQueueHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		name, err := getName(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		winds, err := strconv.Atoi(c.DefaultPostForm("winds", "0"))
+		if err != nil {
+			_ = c.Error(errors.New("invalid number of winds"))
+			return
+		}
+		player := Player{
+			ID:       playerID,
+			Name:     name,
+			Username: c.GetString(KeyUsername),
+		}
+		preferences := QueuePreferences{
+			Ruleset: Ruleset(c.PostForm("ruleset")),
+			Winds:   winds,
+		}
+		err = p.matchmaker.Join(player, preferences)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) leaveQueueHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		err := p.matchmaker.Leave(playerID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) subscribeQueueHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		ch := make(chan QueueUpdate, 1)
+		p.matchmaker.AddClient(playerID, ch)
+
+		notify := c.Request.Context().Done()
+		go func() {
+			<-notify
+			p.matchmaker.RemoveClient(ch)
+		}()
+
+		c.Stream(func(w io.Writer) bool {
+			if update, ok := <-ch; ok {
+				c.SSEvent("", update)
+				return true
+			}
+			return false
+		})
+	}
+}
+
+func (p *Parlour) createTournamentHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playerID := c.GetString(KeyPlayerID)
+		var settings TournamentSettings
+		err := c.ShouldBindJSON(&settings)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		tournament, err := p.tournamentService.Create(playerID, settings)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, tournament.view())
+	}
+}
+
+func (p *Parlour) getTournamentHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tournament := c.MustGet(KeyTournament).(*Tournament)
+		view, err := p.tournamentService.View(tournament)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, view)
+	}
+}
+
+func (p *Parlour) registerEntrantHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tournament := c.MustGet(KeyTournament).(*Tournament)
+		name, err := getName(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		entrant := Entrant{
+			PlayerID: c.GetString(KeyPlayerID),
+			Name:     name,
+			Username: c.GetString(KeyUsername),
+		}
+		err = p.tournamentService.Register(tournament, entrant)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) withdrawEntrantHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tournament := c.MustGet(KeyTournament).(*Tournament)
+		err := p.tournamentService.Withdraw(tournament, c.GetString(KeyPlayerID))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) startSessionHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tournament := c.MustGet(KeyTournament).(*Tournament)
+		err := p.tournamentService.StartSession(tournament, c.GetString(KeyPlayerID))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) setTableResultHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tournament := c.MustGet(KeyTournament).(*Tournament)
+		session, err := strconv.Atoi(c.Param("session"))
+		if err != nil {
+			_ = c.Error(errors.New("invalid session"))
+			return
+		}
+		table, err := strconv.Atoi(c.Param("table"))
+		if err != nil {
+			_ = c.Error(errors.New("invalid table"))
+			return
+		}
+		var result TableResult
+		err = c.ShouldBindJSON(&result)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		err = p.tournamentService.SetTableResult(tournament, c.GetString(KeyPlayerID), session, table, result)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Parlour) setTournamentMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tournament, err := p.tournamentService.Get(c.Param("tournamentID"))
+		if errors.Is(err, errNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Set(KeyTournament, tournament)
+		c.Next()
+	}
+}
+
 func (p *Parlour) setRoomMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		roomID := c.Param("roomID")
@@ -277,17 +943,58 @@ func (p *Parlour) setRoomMiddleware() gin.HandlerFunc {
 
 func (p Parlour) configure(r *gin.Engine) {
 	r.Use(sessions.Sessions(KeySessionName, p.SessionStore))
+	r.Use(p.authenticateToken)
 	r.Use(setPlayerID)
 	r.Use(handleErrors)
+	r.POST("/accounts", p.registerHandler())
+	r.POST("/sessions", p.signInHandler())
+	r.DELETE("/sessions", signOutHandler)
+	r.GET("/me", meHandler)
+	r.GET("/me/rooms", p.myRoomsHandler())
+	r.POST("/tokens", p.createTokenHandler())
+	r.GET("/tokens", p.listTokensHandler())
+	r.DELETE("/tokens/:tokenID", p.revokeTokenHandler())
 	r.POST("/rooms", p.createRoomHandler())
+	r.GET("/rooms", p.listRoomsHandler())
+	r.POST("/queue", p.joinQueueHandler())
+	r.DELETE("/queue", p.leaveQueueHandler())
+	r.GET("/queue/live", p.subscribeQueueHandler())
+	r.GET("/leaderboard", p.leaderboardHandler())
+	r.GET("/players/:playerID/stats", p.playerStatsHandler())
+	r.POST("/tournaments", p.createTournamentHandler())
+	tournament := r.Group("/tournaments/:tournamentID")
+	tournament.Use(p.setTournamentMiddleware())
+	{
+		tournament.GET("", p.getTournamentHandler())
+		tournament.POST("/entrants", p.registerEntrantHandler())
+		tournament.DELETE("/entrants", p.withdrawEntrantHandler())
+		tournament.POST("/sessions", p.startSessionHandler())
+		tournament.PUT("/sessions/:session/tables/:table", p.setTableResultHandler())
+	}
 	room := r.Group("/rooms/:roomID")
 	room.Use(p.setRoomMiddleware())
 	{
 		room.POST("/players", p.joinRoomHandler())
 		room.DELETE("/players", p.leaveRoomHandler())
 		room.GET("/live", p.subscribeRoomHandler())
+		room.GET("/record", exportRecordHandler)
+		room.GET("/transcript", exportTranscriptHandler)
 		room.POST("/actions", p.roomActionsHandler())
 		room.POST("/bots", p.addBotHandler())
+		room.PUT("/stakes", p.setStakesHandler())
+		room.PUT("/settings", p.updateSettingsHandler())
+		room.POST("/invites", p.createInviteHandler())
+		room.DELETE("/players/:seat", p.kickPlayerHandler())
+		room.POST("/players/:seat/bot", p.replaceWithBotHandler())
+		room.PUT("/host", p.transferHostHandler())
+		room.PUT("/lock", p.setLockedHandler(true))
+		room.DELETE("/lock", p.setLockedHandler(false))
+		room.POST("/adjustments", p.adjustScoresHandler())
+		room.POST("/round/void", p.voidRoundHandler())
+		room.PUT("/players/:seat/mute", p.setMutedHandler(true))
+		room.DELETE("/players/:seat/mute", p.setMutedHandler(false))
+		room.POST("/chat", p.postChatHandler())
+		room.DELETE("/chat", p.clearChatHandler())
 		if gin.IsDebugging() {
 			room.PUT("/round/hands/:seat/concealed", setConcealedHandler())
 			room.POST("/round/wall", prependWallHandler())
@@ -1,16 +1,19 @@
 package parlour
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-contrib/sessions/memstore"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 //go:generate ../bin/mockgen -destination mocks_test.go -package parlour -self_package github.com/yi-jiayu/mahjong.go/parlour . RoomRepository
@@ -63,3 +66,143 @@ func TestParlour_joinRoomHandler(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code)
 	assert.Equal(t, "bob", room.Players[1].Name)
 }
+
+func TestParlour_kickPlayerHandler(t *testing.T) {
+	room := newFullRoom()
+	room.ID = "ABCD"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	roomRepository := NewMockRoomRepository(ctrl)
+	roomRepository.EXPECT().Get(room.ID).Return(room, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(roomRepository, memstore.NewStore())
+	parlour.configure(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/rooms/%s/players/1", room.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "not in room", w.Body.String())
+	assert.Len(t, room.Players, 4)
+}
+
+func TestParlour_listRoomsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	roomRepository := NewMockRoomRepository(ctrl)
+	roomRepository.EXPECT().ListOpen(RoomFilter{Ruleset: RulesetShooter}).Return([]RoomListing{
+		{ID: "ABCD", Host: "alice", Players: 2, Ruleset: RulesetShooter, Winds: 4},
+	}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(roomRepository, memstore.NewStore())
+	parlour.configure(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/rooms?ruleset=shooter", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"id":"ABCD","host":"alice","players":2,"ruleset":"shooter","winds":4,"stakes":{"point_value":0,"round_to":0},"has_passcode":false}]`, w.Body.String())
+}
+
+func TestParlour_accounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.accountService.hashCost = bcrypt.MinCost
+	parlour.configure(router)
+
+	do := func(method, path, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	guest := do(http.MethodGet, "/me", "", nil)
+	cookies := guest.Result().Cookies()
+	var me struct {
+		PlayerID string `json:"player_id"`
+		Username string `json:"username"`
+	}
+	_ = json.Unmarshal(guest.Body.Bytes(), &me)
+	guestID := me.PlayerID
+	assert.NotEmpty(t, guestID)
+	assert.Empty(t, me.Username)
+
+	w := do(http.MethodPost, "/accounts", "username=alice&password=password", cookies)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = do(http.MethodGet, "/me", "", cookies)
+	assert.JSONEq(t, fmt.Sprintf(`{"player_id":%q,"username":"alice"}`, guestID), w.Body.String())
+
+	w = do(http.MethodPost, "/accounts", "username=bob&password=password", cookies)
+	assert.Equal(t, "already signed in", w.Body.String())
+
+	w = do(http.MethodDelete, "/sessions", "", cookies)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodGet, "/me", "", cookies)
+	_ = json.Unmarshal(w.Body.Bytes(), &me)
+	assert.NotEqual(t, guestID, me.PlayerID)
+	assert.Empty(t, me.Username)
+
+	device := do(http.MethodGet, "/me", "", nil).Result().Cookies()
+	w = do(http.MethodPost, "/sessions", "username=alice&password=password", device)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodGet, "/me", "", device)
+	assert.JSONEq(t, fmt.Sprintf(`{"player_id":%q,"username":"alice"}`, guestID), w.Body.String())
+}
+
+func TestParlour_accounts_token(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.accountService.hashCost = bcrypt.MinCost
+	parlour.configure(router)
+	_, _ = parlour.accountService.Register("id1", "alice", "password")
+	_, token, _ := parlour.tokenService.Create("id2", "bot")
+
+	for _, tt := range []struct {
+		name, method, path, body string
+	}{
+		{"register", http.MethodPost, "/accounts", "username=bob&password=password"},
+		{"sign in", http.MethodPost, "/sessions", "username=alice&password=password"},
+		{"sign out", http.MethodDelete, "/sessions", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("content-type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer "+token)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, "not allowed with an API token", w.Body.String())
+			assert.Empty(t, w.Result().Cookies())
+		})
+	}
+	_, err := parlour.accountService.AccountRepository.GetByUsername("bob")
+	assert.Equal(t, errNotFound, err)
+}
+
+func Test_reshuffleHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	room := newFullRoom()
+	room.Ruleset = RulesetShooter
+	_ = room.nextRound(SeatDrawNone, time.Now())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(KeyRoom, room)
+	reshuffleHandler()(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, RulesetShooter.rules(), room.Round.Rules)
+}
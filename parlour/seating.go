@@ -0,0 +1,64 @@
+package parlour
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// SeatDraw represents a method of assigning seats at the start of a game.
+type SeatDraw string
+
+// Possible seat draw methods.
+const (
+	// SeatDrawNone keeps players in the order they joined the room.
+	SeatDrawNone SeatDraw = ""
+
+	// SeatDrawRandom assigns seats to players at random.
+	SeatDrawRandom SeatDraw = "random"
+
+	// SeatDrawWind has each player draw a wind tile and sit in the seat of the
+	// wind they drew, with the player who drew east dealing first.
+	SeatDrawWind SeatDraw = "wind"
+)
+
+var windTiles = []mahjong.Tile{
+	mahjong.TileWindsEast,
+	mahjong.TileWindsSouth,
+	mahjong.TileWindsWest,
+	mahjong.TileWindsNorth,
+}
+
+// drawSeats reassigns the players in a room to seats using method and records
+// the outcome as a room event. The player in seat 0 deals first.
+func (r *Room) drawSeats(method SeatDraw, t time.Time) error {
+	var seats []int
+	var tiles []mahjong.Tile
+	switch method {
+	case SeatDrawNone:
+		return nil
+	case SeatDrawRandom:
+		seats = rand.Perm(len(r.Players))
+	case SeatDrawWind:
+		seats = rand.Perm(len(r.Players))
+		tiles = make([]mahjong.Tile, len(seats))
+		for i, seat := range seats {
+			tiles[i] = windTiles[seat]
+		}
+	default:
+		return errors.New("invalid seat draw")
+	}
+	players := make([]Player, len(r.Players))
+	for i, seat := range seats {
+		players[seat] = r.Players[i]
+	}
+	r.Players = players
+	event := newRoomEvent(RoomEventSeatDraw, t)
+	event.SeatDraw = method
+	event.Seats = seats
+	event.Tiles = tiles
+	r.Events = append(r.Events, event)
+	return nil
+}
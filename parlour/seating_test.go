@@ -0,0 +1,80 @@
+package parlour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+func newFullRoom() *Room {
+	r := NewRoom(Player{ID: "id1", Name: "player1"})
+	_ = r.addPlayer(Player{ID: "id2", Name: "player2"})
+	_ = r.addPlayer(Player{ID: "id3", Name: "player3"})
+	_ = r.addPlayer(Player{ID: "id4", Name: "player4"})
+	return r
+}
+
+func TestRoom_drawSeats(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		r := newFullRoom()
+		players := append([]Player{}, r.Players...)
+		err := r.drawSeats(SeatDrawNone, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, players, r.Players)
+		assert.Empty(t, r.Events)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		r := newFullRoom()
+		err := r.drawSeats("dice", time.Now())
+		assert.EqualError(t, err, "invalid seat draw")
+	})
+	t.Run("random", func(t *testing.T) {
+		r := newFullRoom()
+		players := append([]Player{}, r.Players...)
+		err := r.drawSeats(SeatDrawRandom, time.Unix(1, 0))
+		assert.NoError(t, err)
+		assert.Len(t, r.Events, 1)
+		event := r.Events[0]
+		assert.Equal(t, RoomEventSeatDraw, event.Type)
+		assert.Equal(t, int64(1000), event.Time)
+		assert.Equal(t, SeatDrawRandom, event.SeatDraw)
+		assert.Empty(t, event.Tiles)
+		for i, seat := range event.Seats {
+			assert.Equal(t, players[i], r.Players[seat])
+		}
+	})
+	t.Run("wind", func(t *testing.T) {
+		r := newFullRoom()
+		players := append([]Player{}, r.Players...)
+		err := r.drawSeats(SeatDrawWind, time.Now())
+		assert.NoError(t, err)
+		event := r.Events[0]
+		assert.Equal(t, SeatDrawWind, event.SeatDraw)
+		assert.ElementsMatch(t, []mahjong.Tile{
+			mahjong.TileWindsEast,
+			mahjong.TileWindsSouth,
+			mahjong.TileWindsWest,
+			mahjong.TileWindsNorth,
+		}, event.Tiles)
+		for i, tile := range event.Tiles {
+			if tile == mahjong.TileWindsEast {
+				assert.Equal(t, players[i], r.Players[0])
+			}
+		}
+	})
+}
+
+func TestRoom_nextRound_seatDraw(t *testing.T) {
+	r := newFullRoom()
+	players := append([]Player{}, r.Players...)
+	err := r.reduce("id1", Action{Type: ActionNextRound, SeatDraw: SeatDrawWind})
+	assert.NoError(t, err)
+	assert.Equal(t, PhaseInProgress, r.Phase)
+	assert.Equal(t, 0, r.Round.Dealer)
+	assert.Len(t, r.Events, 1)
+	for i, seat := range r.Events[0].Seats {
+		assert.Equal(t, players[i], r.Players[seat])
+	}
+}
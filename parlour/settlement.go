@@ -0,0 +1,159 @@
+package parlour
+
+import (
+	"errors"
+	"sort"
+)
+
+// RoundingMode represents how settlement amounts are rounded.
+type RoundingMode string
+
+// Possible rounding modes.
+const (
+	RoundNearest RoundingMode = "nearest"
+	RoundDown    RoundingMode = "down"
+	RoundUp      RoundingMode = "up"
+)
+
+// Stakes describes how much a game is played for.
+type Stakes struct {
+	// PointValue is the value of a single point in cents.
+	PointValue int `json:"point_value"`
+
+	// RoundTo is the amount in cents each transfer is rounded to a multiple
+	// of, or 0 if transfers should not be rounded.
+	RoundTo int `json:"round_to"`
+
+	// Rounding is how transfers are rounded. The zero value rounds to the
+	// nearest multiple of RoundTo.
+	Rounding RoundingMode `json:"rounding,omitempty"`
+}
+
+func (s Stakes) validate() error {
+	if s.PointValue < 0 {
+		return errors.New("point value cannot be negative")
+	}
+	if s.RoundTo < 0 {
+		return errors.New("round to cannot be negative")
+	}
+	switch s.Rounding {
+	case "", RoundNearest, RoundDown, RoundUp:
+		return nil
+	}
+	return errors.New("rounding is invalid")
+}
+
+func (s Stakes) round(amount int) int {
+	if s.RoundTo <= 1 {
+		return amount
+	}
+	remainder := amount % s.RoundTo
+	switch s.Rounding {
+	case RoundDown:
+		return amount - remainder
+	case RoundUp:
+		if remainder == 0 {
+			return amount
+		}
+		return amount - remainder + s.RoundTo
+	default:
+		if 2*remainder >= s.RoundTo {
+			return amount - remainder + s.RoundTo
+		}
+		return amount - remainder
+	}
+}
+
+// Transfer represents a payment from one player to another when settling up.
+type Transfer struct {
+	// From is the integer offset of the player who pays.
+	From int `json:"from"`
+
+	// To is the integer offset of the player who is paid.
+	To int `json:"to"`
+
+	// Amount is how much is paid in cents.
+	Amount int `json:"amount"`
+}
+
+// Settlement represents how players settle up at the end of a game.
+type Settlement struct {
+	// Balances contains how much each player won or lost in cents.
+	Balances [4]int `json:"balances"`
+
+	// Transfers contains the payments needed to settle the balances.
+	Transfers []Transfer `json:"transfers"`
+}
+
+// settle converts final scores into the fewest transfers needed to settle
+// up.
+func settle(scores [4]int, stakes Stakes) Settlement {
+	var settlement Settlement
+	for i, score := range scores {
+		settlement.Balances[i] = score * stakes.PointValue
+	}
+	transfers := minimalTransfers(settlement.Balances)
+	settlement.Transfers = []Transfer{}
+	for _, transfer := range transfers {
+		transfer.Amount = stakes.round(transfer.Amount)
+		if transfer.Amount > 0 {
+			settlement.Transfers = append(settlement.Transfers, transfer)
+		}
+	}
+	return settlement
+}
+
+type balance struct {
+	seat   int
+	amount int
+}
+
+// minimalTransfers returns the fewest transfers which settle balances. Exact
+// matches between a debtor and a creditor are settled first, which is
+// optimal for four players.
+func minimalTransfers(balances [4]int) []Transfer {
+	var debtors, creditors []balance
+	for seat, amount := range balances {
+		if amount < 0 {
+			debtors = append(debtors, balance{seat: seat, amount: -amount})
+		} else if amount > 0 {
+			creditors = append(creditors, balance{seat: seat, amount: amount})
+		}
+	}
+	var transfers []Transfer
+	for i := range debtors {
+		for j := range creditors {
+			if debtors[i].amount > 0 && debtors[i].amount == creditors[j].amount {
+				transfers = append(transfers, Transfer{
+					From:   debtors[i].seat,
+					To:     creditors[j].seat,
+					Amount: debtors[i].amount,
+				})
+				debtors[i].amount = 0
+				creditors[j].amount = 0
+			}
+		}
+	}
+	for {
+		sort.SliceStable(debtors, func(i, j int) bool {
+			return debtors[i].amount > debtors[j].amount
+		})
+		sort.SliceStable(creditors, func(i, j int) bool {
+			return creditors[i].amount > creditors[j].amount
+		})
+		if len(debtors) == 0 || len(creditors) == 0 || debtors[0].amount == 0 || creditors[0].amount == 0 {
+			return transfers
+		}
+		amount := debtors[0].amount
+		if creditors[0].amount < amount {
+			amount = creditors[0].amount
+		}
+		transfers = append(transfers, Transfer{
+			From:   debtors[0].seat,
+			To:     creditors[0].seat,
+			Amount: amount,
+		})
+		debtors[0].amount -= amount
+		creditors[0].amount -= amount
+	}
+}
@@ -0,0 +1,74 @@
+package parlour
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_minimalTransfers(t *testing.T) {
+	t.Run("settles exact matches directly", func(t *testing.T) {
+		transfers := minimalTransfers([4]int{30, -30, 50, -50})
+		assert.Equal(t, []Transfer{
+			{From: 1, To: 0, Amount: 30},
+			{From: 3, To: 2, Amount: 50},
+		}, transfers)
+	})
+	t.Run("largest debtor pays largest creditor", func(t *testing.T) {
+		transfers := minimalTransfers([4]int{60, -10, -20, -30})
+		assert.Equal(t, []Transfer{
+			{From: 3, To: 0, Amount: 30},
+			{From: 2, To: 0, Amount: 20},
+			{From: 1, To: 0, Amount: 10},
+		}, transfers)
+	})
+	t.Run("nothing to settle", func(t *testing.T) {
+		assert.Empty(t, minimalTransfers([4]int{}))
+	})
+}
+
+func TestStakes_round(t *testing.T) {
+	tests := []struct {
+		name   string
+		stakes Stakes
+		amount int
+		want   int
+	}{
+		{"no rounding", Stakes{}, 123, 123},
+		{"nearest rounds down", Stakes{RoundTo: 10}, 124, 120},
+		{"nearest rounds up", Stakes{RoundTo: 10, Rounding: RoundNearest}, 125, 130},
+		{"down", Stakes{RoundTo: 50, Rounding: RoundDown}, 149, 100},
+		{"up", Stakes{RoundTo: 50, Rounding: RoundUp}, 101, 150},
+		{"up when exact", Stakes{RoundTo: 50, Rounding: RoundUp}, 100, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.stakes.round(tt.amount))
+		})
+	}
+}
+
+func Test_settle(t *testing.T) {
+	settlement := settle([4]int{16, -4, -8, -4}, Stakes{PointValue: 15, RoundTo: 50, Rounding: RoundDown})
+	assert.Equal(t, Settlement{
+		Balances: [4]int{240, -60, -120, -60},
+		Transfers: []Transfer{
+			{From: 2, To: 0, Amount: 100},
+			{From: 1, To: 0, Amount: 50},
+			{From: 3, To: 0, Amount: 50},
+		},
+	}, settlement)
+}
+
+func TestRoom_view_settlement(t *testing.T) {
+	room := NewRoom(Player{ID: "alice"})
+	room.Stakes = Stakes{PointValue: 10}
+	room.Scores = [4]int{2, -2, 0, 0}
+	assert.Nil(t, room.view("alice").Settlement)
+
+	room.Phase = PhaseFinished
+	assert.Equal(t, &Settlement{
+		Balances:  [4]int{20, -20, 0, 0},
+		Transfers: []Transfer{{From: 1, To: 0, Amount: 20}},
+	}, room.view("alice").Settlement)
+}
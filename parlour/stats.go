@@ -0,0 +1,155 @@
+package parlour
+
+import (
+	"sort"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+// MaxStatsElements is the number of most common scoring elements included in
+// a player's statistics.
+const MaxStatsElements = 5
+
+// ElementCount is how many winning hands a scoring element appeared in.
+type ElementCount struct {
+	Element mahjong.Element `json:"element"`
+	Count   int             `json:"count"`
+}
+
+// PlayerStats contains statistics about the rounds a player has played.
+type PlayerStats struct {
+	Games     int `json:"games"`
+	Rounds    int `json:"rounds"`
+	Wins      int `json:"wins"`
+	SelfDraws int `json:"self_draws"`
+	DealIns   int `json:"deal_ins"`
+
+	// WinRate is the proportion of rounds the player won.
+	WinRate float64 `json:"win_rate"`
+
+	// DealInRate is the proportion of rounds in which the player discarded
+	// the winning tile.
+	DealInRate float64 `json:"deal_in_rate"`
+
+	// SelfDrawRate is the proportion of the player's wins which were by
+	// self-draw.
+	SelfDrawRate float64 `json:"self_draw_rate"`
+
+	// AveragePoints is the average number of points the player's winning
+	// hands were worth.
+	AveragePoints float64 `json:"average_points"`
+
+	// Elements contains the scoring elements which most often appeared in the
+	// player's winning hands.
+	Elements []ElementCount `json:"elements"`
+}
+
+// allGames returns the previously completed games in a room, followed by the
+// current game if any rounds have been played in it.
+func (r *Room) allGames() []Game {
+	games := append([]Game{}, r.Games...)
+	if len(r.Results) > 0 {
+		games = append(games, Game{
+			Players: r.Players,
+			Scores:  r.Scores,
+			Results: r.Results,
+			Ledger:  r.Ledger,
+		})
+	}
+	return games
+}
+
+// playedIn returns whether a player was seated in a game.
+func (g Game) playedIn(playerID string) bool {
+	return g.seat(playerID) != -1
+}
+
+func (g Game) seat(playerID string) int {
+	for i, player := range g.Players {
+		if player.ID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// computeStats aggregates a player's statistics over the results of games.
+// Games the player was not seated in are ignored.
+func computeStats(playerID string, games []Game) PlayerStats {
+	stats := PlayerStats{
+		Elements: []ElementCount{},
+	}
+	points := 0
+	elements := make(map[mahjong.Element]int)
+	for _, game := range games {
+		seat := game.seat(playerID)
+		if seat == -1 {
+			continue
+		}
+		stats.Games++
+		for _, result := range game.Results {
+			if result.Voided {
+				continue
+			}
+			stats.Rounds++
+			if result.Loser == seat {
+				stats.DealIns++
+			}
+			if result.Winner != seat {
+				continue
+			}
+			stats.Wins++
+			if result.Loser == -1 {
+				stats.SelfDraws++
+			}
+			points += result.Points
+			seen := make(map[mahjong.Element]bool)
+			for _, tai := range result.Breakdown {
+				if !seen[tai.Element] {
+					seen[tai.Element] = true
+					elements[tai.Element]++
+				}
+			}
+		}
+	}
+	if stats.Rounds > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(stats.Rounds)
+		stats.DealInRate = float64(stats.DealIns) / float64(stats.Rounds)
+	}
+	if stats.Wins > 0 {
+		stats.SelfDrawRate = float64(stats.SelfDraws) / float64(stats.Wins)
+		stats.AveragePoints = float64(points) / float64(stats.Wins)
+	}
+	for element, count := range elements {
+		stats.Elements = append(stats.Elements, ElementCount{Element: element, Count: count})
+	}
+	sort.Slice(stats.Elements, func(i, j int) bool {
+		if stats.Elements[i].Count != stats.Elements[j].Count {
+			return stats.Elements[i].Count > stats.Elements[j].Count
+		}
+		return stats.Elements[i].Element < stats.Elements[j].Element
+	})
+	if len(stats.Elements) > MaxStatsElements {
+		stats.Elements = stats.Elements[:MaxStatsElements]
+	}
+	return stats
+}
+
+type statsService struct {
+	RoomRepository RoomRepository
+}
+
+func newStatsService(roomRepository RoomRepository) *statsService {
+	return &statsService{
+		RoomRepository: roomRepository,
+	}
+}
+
+// Stats returns the statistics of a player over every game they have played.
+func (s *statsService) Stats(playerID string) (PlayerStats, error) {
+	games, err := s.RoomRepository.ListGames(playerID)
+	if err != nil {
+		return PlayerStats{}, &Error{error: err, internal: true}
+	}
+	return computeStats(playerID, games), nil
+}
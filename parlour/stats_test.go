@@ -0,0 +1,128 @@
+package parlour
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+func fixtureGames() []Game {
+	return []Game{
+		{
+			Players: []Player{{ID: "id1"}, {ID: "id2"}, {ID: "id3"}, {ID: "id4"}},
+			Results: []mahjong.Result{
+				{Winner: 0, Loser: 2, Points: 3, Breakdown: []mahjong.Tai{
+					{Element: mahjong.ElementHalfFlush, Points: 2},
+					{Element: mahjong.ElementFlower, Points: 1},
+				}},
+				{Winner: 0, Loser: -1, Points: 5, Breakdown: []mahjong.Tai{
+					{Element: mahjong.ElementFullFlush, Points: 4},
+					{Element: mahjong.ElementFlower, Points: 1},
+				}},
+				{Winner: 1, Loser: 0, Points: 1, Breakdown: []mahjong.Tai{
+					{Element: mahjong.ElementPingHu, Points: 1},
+				}},
+				{Winner: -1, Loser: -1},
+				{Winner: 0, Loser: 3, Points: 1, Voided: true},
+			},
+		},
+		{
+			Players: []Player{{ID: "id2"}, {ID: "id1"}, {ID: "id3"}, {ID: "id4"}},
+			Results: []mahjong.Result{
+				{Winner: 1, Loser: 3, Points: 2, Breakdown: []mahjong.Tai{
+					{Element: mahjong.ElementFlower, Points: 1},
+					{Element: mahjong.ElementFlower, Points: 1},
+				}},
+				{Winner: 2, Loser: 1, Points: 1},
+			},
+		},
+		{
+			Players: []Player{{ID: "id5"}, {ID: "id2"}, {ID: "id3"}, {ID: "id4"}},
+			Results: []mahjong.Result{
+				{Winner: 0, Loser: 1, Points: 5},
+			},
+		},
+	}
+}
+
+func Test_computeStats(t *testing.T) {
+	t.Run("player with games", func(t *testing.T) {
+		stats := computeStats("id1", fixtureGames())
+		assert.Equal(t, 2, stats.Games)
+		assert.Equal(t, 6, stats.Rounds)
+		assert.Equal(t, 3, stats.Wins)
+		assert.Equal(t, 1, stats.SelfDraws)
+		assert.Equal(t, 2, stats.DealIns)
+		assert.Equal(t, 0.5, stats.WinRate)
+		assert.InDelta(t, 1.0/3, stats.DealInRate, 1e-9)
+		assert.InDelta(t, 1.0/3, stats.SelfDrawRate, 1e-9)
+		assert.InDelta(t, 10.0/3, stats.AveragePoints, 1e-9)
+		assert.Equal(t, []ElementCount{
+			{Element: mahjong.ElementFlower, Count: 3},
+			{Element: mahjong.ElementFullFlush, Count: 1},
+			{Element: mahjong.ElementHalfFlush, Count: 1},
+		}, stats.Elements)
+	})
+	t.Run("player without wins", func(t *testing.T) {
+		stats := computeStats("id4", fixtureGames())
+		assert.Equal(t, 3, stats.Games)
+		assert.Equal(t, 7, stats.Rounds)
+		assert.Equal(t, 0, stats.Wins)
+		assert.Equal(t, 1, stats.DealIns)
+		assert.Equal(t, 0.0, stats.SelfDrawRate)
+		assert.Equal(t, 0.0, stats.AveragePoints)
+		assert.Empty(t, stats.Elements)
+	})
+	t.Run("unknown player", func(t *testing.T) {
+		stats := computeStats("id6", fixtureGames())
+		assert.Equal(t, PlayerStats{Elements: []ElementCount{}}, stats)
+	})
+}
+
+func TestInMemoryRoomRepository_ListGames(t *testing.T) {
+	repo := NewInMemoryRoomRepository()
+	games := fixtureGames()
+	room := newFullRoom()
+	room.Games = games[:1]
+	room.Players = games[1].Players
+	room.Results = games[1].Results
+	_ = repo.Save(room)
+	other := newFullRoom()
+	other.Players = games[2].Players
+	other.Results = games[2].Results
+	_ = repo.Save(other)
+	_ = repo.Save(NewRoom(Player{ID: "id1"}))
+
+	got, err := repo.ListGames("id1")
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	got, err = repo.ListGames("id5")
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+}
+
+func TestParlour_playerStatsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	repo := NewInMemoryRoomRepository()
+	room := newFullRoom()
+	room.Games = fixtureGames()
+	_ = repo.Save(room)
+	parlour := New(repo, memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/players/id1/stats", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats PlayerStats
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(t, computeStats("id1", fixtureGames()), stats)
+}
@@ -0,0 +1,148 @@
+package parlour
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type TokenRepository interface {
+	Create(token *APIToken) error
+	GetByHash(hash []byte) (*APIToken, error)
+	List(playerID string) ([]APIToken, error)
+	Delete(playerID, id string) error
+}
+
+type InMemoryTokenRepository struct {
+	sync.RWMutex
+	tokens map[string]*APIToken
+}
+
+func NewInMemoryTokenRepository() *InMemoryTokenRepository {
+	return &InMemoryTokenRepository{
+		tokens: map[string]*APIToken{},
+	}
+}
+
+func (r *InMemoryTokenRepository) Create(token *APIToken) error {
+	r.Lock()
+	defer r.Unlock()
+	r.tokens[token.ID] = token
+	return nil
+}
+
+func (r *InMemoryTokenRepository) GetByHash(hash []byte) (*APIToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, token := range r.tokens {
+		if bytes.Equal(token.Hash, hash) {
+			return token, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *InMemoryTokenRepository) List(playerID string) ([]APIToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+	tokens := []APIToken{}
+	for _, token := range r.tokens {
+		if token.PlayerID == playerID {
+			tokens = append(tokens, *token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (r *InMemoryTokenRepository) Delete(playerID, id string) error {
+	r.Lock()
+	defer r.Unlock()
+	token, ok := r.tokens[id]
+	if !ok || token.PlayerID != playerID {
+		return errNotFound
+	}
+	delete(r.tokens, id)
+	return nil
+}
+
+type PostgresTokenRepository struct {
+	conn Conn
+}
+
+func (p *PostgresTokenRepository) Create(token *APIToken) error {
+	_, err := p.conn.Exec(context.Background(),
+		"insert into api_tokens (id, player_id, name, hash, created_at) values ($1, $2, $3, $4, $5)",
+		token.ID,
+		token.PlayerID,
+		token.Name,
+		token.Hash,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating token: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresTokenRepository) GetByHash(hash []byte) (*APIToken, error) {
+	var token APIToken
+	err := p.conn.QueryRow(
+		context.Background(),
+		"select id, player_id, name, hash, created_at from api_tokens where hash = $1", hash,
+	).Scan(&token.ID, &token.PlayerID, &token.Name, &token.Hash, &token.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+	return &token, nil
+}
+
+func (p *PostgresTokenRepository) List(playerID string) ([]APIToken, error) {
+	rows, err := p.conn.Query(context.Background(),
+		"select id, player_id, name, hash, created_at from api_tokens where player_id = $1 order by created_at", playerID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tokens: %w", err)
+	}
+	defer rows.Close()
+	tokens := []APIToken{}
+	for rows.Next() {
+		var token APIToken
+		err := rows.Scan(&token.ID, &token.PlayerID, &token.Name, &token.Hash, &token.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error listing tokens: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (p *PostgresTokenRepository) Delete(playerID, id string) error {
+	tag, err := p.conn.Exec(context.Background(),
+		"delete from api_tokens where id = $1 and player_id = $2", id, playerID)
+	if err != nil {
+		return fmt.Errorf("error deleting token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+func NewPostgresTokenRepository(conn Conn) *PostgresTokenRepository {
+	return &PostgresTokenRepository{
+		conn: conn,
+	}
+}
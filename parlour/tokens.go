@@ -0,0 +1,151 @@
+package parlour
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenPrefix is prepended to API tokens to make them easy to recognise.
+const tokenPrefix = "pt_"
+
+var (
+	errInvalidToken    = errors.New("invalid token")
+	errTokenNotAllowed = errors.New("not allowed with an API token")
+)
+
+// APIToken lets scripts and bots act as a player without a session cookie.
+// Only a hash of the token itself is stored.
+type APIToken struct {
+	ID        string    `json:"id"`
+	PlayerID  string    `json:"-"`
+	Name      string    `json:"name"`
+	Hash      []byte    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type tokenService struct {
+	TokenRepository TokenRepository
+}
+
+func newTokenService(tokenRepository TokenRepository) *tokenService {
+	return &tokenService{
+		TokenRepository: tokenRepository,
+	}
+}
+
+func randomString(n int) string {
+	data := make([]byte, n)
+	_, err := rand.Read(data)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+// Create issues a new token for playerID. The token itself is only returned
+// here and cannot be recovered later.
+func (s *tokenService) Create(playerID, name string) (*APIToken, string, error) {
+	if len(name) > 64 {
+		return nil, "", &Error{error: errors.New("name too long")}
+	}
+	token := tokenPrefix + randomString(32)
+	apiToken := &APIToken{
+		ID:        randomString(9),
+		PlayerID:  playerID,
+		Name:      name,
+		Hash:      hashToken(token),
+		CreatedAt: time.Now(),
+	}
+	err := s.TokenRepository.Create(apiToken)
+	if err != nil {
+		return nil, "", &Error{error: err, internal: true}
+	}
+	return apiToken, token, nil
+}
+
+// List returns playerID's tokens in the order they were created.
+func (s *tokenService) List(playerID string) ([]APIToken, error) {
+	tokens, err := s.TokenRepository.List(playerID)
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	return tokens, nil
+}
+
+// Revoke deletes one of playerID's tokens.
+func (s *tokenService) Revoke(playerID, id string) error {
+	err := s.TokenRepository.Delete(playerID, id)
+	if errors.Is(err, errNotFound) {
+		return &Error{error: err}
+	}
+	if err != nil {
+		return &Error{error: err, internal: true}
+	}
+	return nil
+}
+
+// Authenticate returns the player ID a token was issued for.
+func (s *tokenService) Authenticate(token string) (string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", errInvalidToken
+	}
+	apiToken, err := s.TokenRepository.GetByHash(hashToken(token))
+	if errors.Is(err, errNotFound) {
+		return "", errInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+	return apiToken.PlayerID, nil
+}
+
+// authenticateToken sets the player ID from a bearer token in the
+// Authorization header, if there is one, along with the username if the
+// player has an account. Requests with an invalid token are rejected.
+func (p *Parlour) authenticateToken(c *gin.Context) {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		c.Next()
+		return
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	playerID, err := p.tokenService.Authenticate(token)
+	if errors.Is(err, errInvalidToken) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		fmt.Printf("error authenticating token: %v\n", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	account, err := p.accountService.AccountRepository.GetByID(playerID)
+	if err != nil && !errors.Is(err, errNotFound) {
+		fmt.Printf("error getting account: %v\n", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Set(KeyPlayerID, playerID)
+	c.Set(KeyAPIToken, true)
+	if account != nil {
+		c.Set(KeyUsername, account.Username)
+	}
+	c.Next()
+}
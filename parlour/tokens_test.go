@@ -0,0 +1,116 @@
+package parlour
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_tokenService(t *testing.T) {
+	s := newTokenService(NewInMemoryTokenRepository())
+	apiToken, token, err := s.Create("id1", "my bot")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, tokenPrefix))
+	assert.NotContains(t, string(apiToken.Hash), token)
+
+	playerID, err := s.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "id1", playerID)
+
+	_, err = s.Authenticate(tokenPrefix + "wrong")
+	assert.Equal(t, errInvalidToken, err)
+
+	other, _, _ := s.Create("id2", "")
+	tokens, err := s.List("id1")
+	assert.NoError(t, err)
+	assert.Equal(t, []APIToken{*apiToken}, tokens)
+	assert.NotContains(t, tokens, *other)
+
+	err = s.Revoke("id2", apiToken.ID)
+	assert.EqualError(t, err, "not found")
+	err = s.Revoke("id1", apiToken.ID)
+	assert.NoError(t, err)
+	_, err = s.Authenticate(token)
+	assert.Equal(t, errInvalidToken, err)
+}
+
+func TestParlour_authenticateToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+	_, token, _ := parlour.tokenService.Create("id1", "")
+
+	do := func(authorization string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("Bearer " + token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var me struct {
+		PlayerID string `json:"player_id"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &me)
+	assert.Equal(t, "id1", me.PlayerID)
+	assert.Empty(t, w.Result().Cookies())
+
+	assert.Equal(t, http.StatusUnauthorized, do("Bearer "+tokenPrefix+"wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("Basic abc").Code)
+}
+
+func TestParlour_tokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+	parlour.accountService.hashCost = bcrypt.MinCost
+	_, _ = parlour.accountService.Register("id1", "alice", "password")
+	apiToken, token, _ := parlour.tokenService.Create("id1", "bot")
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("sets username of account", func(t *testing.T) {
+		w := do(http.MethodGet, "/me")
+		var me struct {
+			Username string `json:"username"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &me)
+		assert.Equal(t, "alice", me.Username)
+	})
+	t.Run("lists tokens", func(t *testing.T) {
+		w := do(http.MethodGet, "/tokens")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":"`+apiToken.ID+`"`)
+		assert.NotContains(t, w.Body.String(), "hash")
+	})
+	t.Run("cannot create tokens", func(t *testing.T) {
+		w := do(http.MethodPost, "/tokens")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "not allowed with an API token", w.Body.String())
+	})
+	t.Run("cannot revoke tokens", func(t *testing.T) {
+		w := do(http.MethodDelete, "/tokens/"+apiToken.ID)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		tokens, _ := parlour.tokenService.List("id1")
+		assert.Len(t, tokens, 1)
+	})
+}
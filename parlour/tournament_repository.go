@@ -0,0 +1,96 @@
+package parlour
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type TournamentRepository interface {
+	Save(tournament *Tournament) error
+	Get(id string) (*Tournament, error)
+}
+
+func newTournamentID() string {
+	return randomString(9)
+}
+
+type InMemoryTournamentRepository struct {
+	sync.RWMutex
+	tournaments map[string]*Tournament
+}
+
+func NewInMemoryTournamentRepository() *InMemoryTournamentRepository {
+	return &InMemoryTournamentRepository{
+		tournaments: map[string]*Tournament{},
+	}
+}
+
+func (r *InMemoryTournamentRepository) Save(tournament *Tournament) error {
+	r.Lock()
+	defer r.Unlock()
+	if tournament.ID == "" {
+		tournament.ID = newTournamentID()
+	}
+	r.tournaments[tournament.ID] = tournament
+	return nil
+}
+
+func (r *InMemoryTournamentRepository) Get(id string) (*Tournament, error) {
+	r.RLock()
+	defer r.RUnlock()
+	tournament, ok := r.tournaments[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return tournament, nil
+}
+
+type PostgresTournamentRepository struct {
+	conn Conn
+}
+
+func (p *PostgresTournamentRepository) Save(tournament *Tournament) error {
+	if tournament.ID == "" {
+		tournament.ID = newTournamentID()
+	}
+	_, err := p.conn.Exec(context.Background(), `insert into tournaments (id, organiser, settings, phase, entrants, sessions)
+values ($1, $2, $3, $4, $5, $6)
+on conflict (id) do update set phase=excluded.phase,
+                               entrants=excluded.entrants,
+                               sessions=excluded.sessions`,
+		tournament.ID,
+		tournament.Organiser,
+		tournament.Settings,
+		tournament.Phase,
+		tournament.Entrants,
+		tournament.Sessions,
+	)
+	if err != nil {
+		return fmt.Errorf("error saving tournament: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresTournamentRepository) Get(id string) (*Tournament, error) {
+	var tournament Tournament
+	err := p.conn.QueryRow(context.Background(),
+		"select id, organiser, settings, phase, entrants, sessions from tournaments where id = $1", id,
+	).Scan(&tournament.ID, &tournament.Organiser, &tournament.Settings, &tournament.Phase, &tournament.Entrants, &tournament.Sessions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting tournament: %w", err)
+	}
+	return &tournament, nil
+}
+
+func NewPostgresTournamentRepository(conn Conn) *PostgresTournamentRepository {
+	return &PostgresTournamentRepository{
+		conn: conn,
+	}
+}
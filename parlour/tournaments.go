@@ -0,0 +1,730 @@
+package parlour
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Pairing is the method used to seat entrants at tables for each session of
+// a tournament.
+type Pairing string
+
+// Possible pairings.
+const (
+	// PairingSwiss seats entrants randomly for the first session, and with
+	// entrants near them in the standings afterwards.
+	PairingSwiss Pairing = "swiss"
+
+	// PairingRotation rotates entrants between tables so that they meet the
+	// opponents they have played least often. Entrants only meet the same
+	// opponent twice once it cannot be avoided, such as in every session
+	// after the first with eight entrants.
+	PairingRotation Pairing = "rotation"
+)
+
+// TournamentPhase is the stage a tournament is at.
+type TournamentPhase string
+
+// Possible tournament phases.
+const (
+	TournamentRegistration TournamentPhase = "registration"
+	TournamentInProgress   TournamentPhase = "in_progress"
+	TournamentFinished     TournamentPhase = "finished"
+)
+
+// Limits on the size of a tournament.
+const (
+	MaxTournamentEntrants = 64
+	MaxTournamentSessions = 16
+)
+
+// placementPoints are the points awarded for finishing first, second, third
+// and fourth at a table.
+var placementPoints = [4]int{3, 2, 1, 0}
+
+var (
+	errNotOrganiser       = errors.New("not organiser")
+	errRegistrationClosed = errors.New("registration closed")
+)
+
+// TournamentSettings contains the settings a tournament is created with.
+type TournamentSettings struct {
+	Name    string  `json:"name"`
+	Ruleset Ruleset `json:"ruleset"`
+
+	// Winds is the number of prevailing winds played at each table, from 1
+	// to 4. Zero means a full game of four winds.
+	Winds int `json:"winds"`
+
+	Pairing Pairing `json:"pairing"`
+
+	// Sessions is the number of sessions in the tournament.
+	Sessions int `json:"sessions"`
+}
+
+func (s *TournamentSettings) validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(s.Name) > 64 {
+		return errors.New("name too long")
+	}
+	if s.Ruleset == "" {
+		s.Ruleset = RulesetDefault
+	}
+	if _, ok := rulesets[s.Ruleset]; !ok {
+		return errors.New("invalid ruleset")
+	}
+	if s.Winds < 0 || s.Winds > 4 {
+		return errors.New("invalid number of winds")
+	}
+	if s.Pairing == "" {
+		s.Pairing = PairingSwiss
+	}
+	if s.Pairing != PairingSwiss && s.Pairing != PairingRotation {
+		return errors.New("invalid pairing")
+	}
+	if s.Sessions < 1 || s.Sessions > MaxTournamentSessions {
+		return errors.New("invalid number of sessions")
+	}
+	return nil
+}
+
+// Entrant is a player registered for a tournament.
+type Entrant struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Username string `json:"username,omitempty"`
+}
+
+// TournamentTable is a room in which four entrants play during a session.
+type TournamentTable struct {
+	RoomID string `json:"room_id"`
+
+	// Entrants contains the indices of the entrants seated at the table.
+	Entrants [4]int `json:"entrants"`
+
+	// Scores contains the final score of each entrant at the table, in the
+	// same order as Entrants.
+	Scores [4]int `json:"scores"`
+
+	Finished bool `json:"finished"`
+
+	// Error is why the table's result could not be recorded from its room.
+	// The organiser can record the result instead.
+	Error string `json:"error,omitempty"`
+}
+
+// TournamentSession is a round of games played simultaneously at every
+// table.
+type TournamentSession struct {
+	Tables []TournamentTable `json:"tables"`
+
+	// Pending is set until a room has been created and a game started for
+	// every table, so that a session which failed to start can be resumed.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Standing is an entrant's position in a tournament.
+type Standing struct {
+	Rank    int     `json:"rank"`
+	Entrant Entrant `json:"entrant"`
+
+	// Points is the total of the entrant's placement points.
+	Points int `json:"points"`
+
+	// Score is the total of the entrant's final scores, which breaks ties in
+	// points.
+	Score int `json:"score"`
+
+	// Wins is the number of tables the entrant finished first at, which
+	// breaks ties in points and score.
+	Wins int `json:"wins"`
+
+	Tables int `json:"tables"`
+}
+
+type Tournament struct {
+	ID        string
+	Organiser string
+	Settings  TournamentSettings
+	Phase     TournamentPhase
+	Entrants  []Entrant
+	Sessions  []TournamentSession
+
+	sync.Mutex
+}
+
+type TournamentView struct {
+	ID        string              `json:"id"`
+	Organiser string              `json:"organiser"`
+	Settings  TournamentSettings  `json:"settings"`
+	Phase     TournamentPhase     `json:"phase"`
+	Entrants  []Entrant           `json:"entrants"`
+	Sessions  []TournamentSession `json:"sessions"`
+	Standings []Standing          `json:"standings"`
+}
+
+func NewTournament(organiser string, settings TournamentSettings) *Tournament {
+	return &Tournament{
+		Organiser: organiser,
+		Settings:  settings,
+		Phase:     TournamentRegistration,
+		Entrants:  []Entrant{},
+		Sessions:  []TournamentSession{},
+	}
+}
+
+// view returns a copy of a tournament which can be used without holding its
+// lock.
+func (t *Tournament) view() TournamentView {
+	sessions := make([]TournamentSession, len(t.Sessions))
+	for i, session := range t.Sessions {
+		sessions[i].Tables = append([]TournamentTable{}, session.Tables...)
+	}
+	return TournamentView{
+		ID:        t.ID,
+		Organiser: t.Organiser,
+		Settings:  t.Settings,
+		Phase:     t.Phase,
+		Entrants:  append([]Entrant{}, t.Entrants...),
+		Sessions:  sessions,
+		Standings: t.standings(),
+	}
+}
+
+func (t *Tournament) entrant(playerID string) int {
+	for i, entrant := range t.Entrants {
+		if entrant.PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Tournament) register(entrant Entrant) error {
+	if t.Phase != TournamentRegistration {
+		return errRegistrationClosed
+	}
+	if t.entrant(entrant.PlayerID) != -1 {
+		return errors.New("already registered")
+	}
+	if len(t.Entrants) >= MaxTournamentEntrants {
+		return errors.New("tournament full")
+	}
+	name := entrant.Name
+	for i := 2; t.nameTaken(entrant.Name); i++ {
+		entrant.Name = fmt.Sprintf("%s %d", name, i)
+	}
+	t.Entrants = append(t.Entrants, entrant)
+	return nil
+}
+
+func (t *Tournament) nameTaken(name string) bool {
+	for _, entrant := range t.Entrants {
+		if entrant.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tournament) withdraw(playerID string) error {
+	if t.Phase != TournamentRegistration {
+		return errRegistrationClosed
+	}
+	i := t.entrant(playerID)
+	if i == -1 {
+		return errors.New("not registered")
+	}
+	t.Entrants = append(t.Entrants[:i], t.Entrants[i+1:]...)
+	return nil
+}
+
+// sessionFinished returns whether every table in the latest session has
+// finished.
+func (t *Tournament) sessionFinished() bool {
+	if len(t.Sessions) == 0 {
+		return true
+	}
+	for _, table := range t.Sessions[len(t.Sessions)-1].Tables {
+		if !table.Finished {
+			return false
+		}
+	}
+	return true
+}
+
+// seatings returns the entrants to seat at each table for the next session.
+func (t *Tournament) seatings(rng *rand.Rand) ([][4]int, error) {
+	if t.Phase == TournamentFinished || len(t.Sessions) >= t.Settings.Sessions {
+		return nil, errors.New("no more sessions")
+	}
+	if !t.sessionFinished() {
+		return nil, errors.New("session in progress")
+	}
+	n := len(t.Entrants)
+	if n < 4 || n%4 != 0 {
+		return nil, errors.New("number of entrants must be a multiple of four")
+	}
+	tables := make([][4]int, n/4)
+	switch t.Settings.Pairing {
+	case PairingRotation:
+		t.rotate(tables)
+	default:
+		var order []int
+		if len(t.Sessions) == 0 {
+			order = rng.Perm(n)
+		} else {
+			for _, standing := range t.standings() {
+				order = append(order, t.entrant(standing.Entrant.PlayerID))
+			}
+		}
+		for i, entrant := range order {
+			tables[i/4][i%4] = entrant
+		}
+	}
+	return tables, nil
+}
+
+// rotate seats the entrants at tables so that they meet the opponents they
+// have played least often. Entrants are first arranged in four rows of one
+// entrant per table, with each row shifted by a different number of tables
+// every session. This alone repeats pairings whenever the number of tables
+// shares a factor with the shifts, so entrants are then swapped between
+// tables for as long as that reduces how often the entrants at each table
+// have already met.
+func (t *Tournament) rotate(tables [][4]int) {
+	n := len(t.Entrants)
+	met := make([][]int, n)
+	for i := range met {
+		met[i] = make([]int, n)
+	}
+	for _, session := range t.Sessions {
+		for _, table := range session.Tables {
+			for _, a := range table.Entrants {
+				for _, b := range table.Entrants {
+					if a != b {
+						met[a][b]++
+					}
+				}
+			}
+		}
+	}
+	// squaring the number of meetings prefers meeting two opponents for a
+	// second time over meeting one opponent for a third time
+	cost := func(table [4]int) int {
+		c := 0
+		for i, a := range table {
+			for _, b := range table[i+1:] {
+				c += met[a][b] * met[a][b]
+			}
+		}
+		return c
+	}
+	session := len(t.Sessions)
+	for i := 0; i < n; i++ {
+		row, column := i/len(tables), i%len(tables)
+		tables[(column+row*session)%len(tables)][row] = i
+	}
+	for improved := true; improved; {
+		improved = false
+		for i := range tables {
+			for j := i + 1; j < len(tables); j++ {
+				for a := range tables[i] {
+					for b := range tables[j] {
+						before := cost(tables[i]) + cost(tables[j])
+						tables[i][a], tables[j][b] = tables[j][b], tables[i][a]
+						if cost(tables[i])+cost(tables[j]) < before {
+							improved = true
+						} else {
+							tables[i][a], tables[j][b] = tables[j][b], tables[i][a]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// addSession adds a session with tables in the given rooms.
+func (t *Tournament) addSession(seatings [][4]int, roomIDs []string) {
+	session := TournamentSession{
+		Tables: make([]TournamentTable, len(seatings)),
+	}
+	for i, entrants := range seatings {
+		session.Tables[i] = TournamentTable{
+			RoomID:   roomIDs[i],
+			Entrants: entrants,
+		}
+	}
+	t.Sessions = append(t.Sessions, session)
+	t.Phase = TournamentInProgress
+}
+
+// recordTable records the final scores of the players in a finished room.
+// Entrants whose seats were taken over by a bot are matched by the seat they
+// vacated. If an entrant is not seated in the room, the table is left for the
+// organiser to record and the reason is kept in its Error.
+func (t *Tournament) recordTable(room *Room) error {
+	if room.Phase != PhaseFinished {
+		return errors.New("game not finished")
+	}
+	for i := range t.Sessions {
+		for j := range t.Sessions[i].Tables {
+			table := &t.Sessions[i].Tables[j]
+			if table.RoomID != room.ID {
+				continue
+			}
+			if table.Finished {
+				return nil
+			}
+			var scores [4]int
+			for k, entrant := range table.Entrants {
+				seat := room.seat(t.Entrants[entrant].PlayerID)
+				if seat == -1 {
+					table.Error = fmt.Sprintf("entrant %s not seated in room %s", t.Entrants[entrant].PlayerID, room.ID)
+					return nil
+				}
+				scores[k] = room.Scores[seat]
+			}
+			table.Scores = scores
+			table.Finished = true
+			table.Error = ""
+			t.checkFinished()
+			return nil
+		}
+	}
+	return errors.New("table not found")
+}
+
+// TableResult contains the final scores of the entrants at a table, in the
+// same order as the table's entrants.
+type TableResult struct {
+	Scores [4]int `json:"scores"`
+}
+
+// setTableResult lets the organiser record the result of a table when it
+// cannot be recorded from its room, or correct a recorded result.
+func (t *Tournament) setTableResult(playerID string, session, table int, result TableResult) error {
+	if playerID != t.Organiser {
+		return errNotOrganiser
+	}
+	if session < 0 || session >= len(t.Sessions) {
+		return errors.New("invalid session")
+	}
+	tables := t.Sessions[session].Tables
+	if table < 0 || table >= len(tables) {
+		return errors.New("invalid table")
+	}
+	sum := 0
+	for _, score := range result.Scores {
+		sum += score
+	}
+	if sum != 0 {
+		return errors.New("scores must add up to zero")
+	}
+	tables[table].Scores = result.Scores
+	tables[table].Finished = true
+	tables[table].Error = ""
+	t.checkFinished()
+	return nil
+}
+
+// pending returns whether the latest session has yet to start at every table.
+func (t *Tournament) pending() bool {
+	return len(t.Sessions) > 0 && t.Sessions[len(t.Sessions)-1].Pending
+}
+
+// checkFinished finishes the tournament once every table in its last session
+// has finished.
+func (t *Tournament) checkFinished() {
+	if len(t.Sessions) == t.Settings.Sessions && t.sessionFinished() {
+		t.Phase = TournamentFinished
+	}
+}
+
+// placements returns the placement of each entrant at a finished table.
+// Entrants who tie share the higher placement.
+func (table TournamentTable) placements() [4]int {
+	var placements [4]int
+	for i := range table.Scores {
+		for j := range table.Scores {
+			if table.Scores[j] > table.Scores[i] {
+				placements[i]++
+			}
+		}
+	}
+	return placements
+}
+
+// standings returns every entrant's position in the tournament, ranked by
+// placement points, then total score, then number of wins. Entrants who are
+// tied on all three share a rank.
+func (t *Tournament) standings() []Standing {
+	standings := make([]Standing, len(t.Entrants))
+	for i, entrant := range t.Entrants {
+		standings[i].Entrant = entrant
+	}
+	for _, session := range t.Sessions {
+		for _, table := range session.Tables {
+			if !table.Finished {
+				continue
+			}
+			placements := table.placements()
+			for k, entrant := range table.Entrants {
+				standing := &standings[entrant]
+				standing.Points += placementPoints[placements[k]]
+				standing.Score += table.Scores[k]
+				if placements[k] == 0 {
+					standing.Wins++
+				}
+				standing.Tables++
+			}
+		}
+	}
+	tied := func(a, b Standing) bool {
+		return a.Points == b.Points && a.Score == b.Score && a.Wins == b.Wins
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Wins > b.Wins
+	})
+	for i := range standings {
+		if i > 0 && tied(standings[i], standings[i-1]) {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+	return standings
+}
+
+type tournamentService struct {
+	TournamentRepository TournamentRepository
+
+	roomService *roomService
+
+	cache map[string]*Tournament
+	sync.Mutex
+}
+
+func newTournamentService(tournamentRepository TournamentRepository, roomService *roomService) *tournamentService {
+	return &tournamentService{
+		TournamentRepository: tournamentRepository,
+		roomService:          roomService,
+		cache:                make(map[string]*Tournament),
+	}
+}
+
+func (s *tournamentService) Get(id string) (*Tournament, error) {
+	s.Lock()
+	defer s.Unlock()
+	if tournament, ok := s.cache[id]; ok {
+		return tournament, nil
+	}
+	tournament, err := s.TournamentRepository.Get(id)
+	if errors.Is(err, errNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	s.cache[tournament.ID] = tournament
+	return tournament, nil
+}
+
+func (s *tournamentService) Create(organiser string, settings TournamentSettings) (*Tournament, error) {
+	err := settings.validate()
+	if err != nil {
+		return nil, &Error{error: err}
+	}
+	tournament := NewTournament(organiser, settings)
+	s.Lock()
+	defer s.Unlock()
+	err = s.TournamentRepository.Save(tournament)
+	if err != nil {
+		return nil, &Error{error: err, internal: true}
+	}
+	s.cache[tournament.ID] = tournament
+	return tournament, nil
+}
+
+// update locks a tournament, applies f to it and saves it if f succeeds.
+func (s *tournamentService) update(tournament *Tournament, f func(t *Tournament) error) error {
+	tournament.Lock()
+	defer tournament.Unlock()
+	err := f(tournament)
+	if err != nil {
+		return &Error{error: err}
+	}
+	err = s.TournamentRepository.Save(tournament)
+	if err != nil {
+		return &Error{error: err, internal: true}
+	}
+	return nil
+}
+
+func (s *tournamentService) Register(tournament *Tournament, entrant Entrant) error {
+	return s.update(tournament, func(t *Tournament) error {
+		return t.register(entrant)
+	})
+}
+
+func (s *tournamentService) Withdraw(tournament *Tournament, playerID string) error {
+	return s.update(tournament, func(t *Tournament) error {
+		return t.withdraw(playerID)
+	})
+}
+
+// StartSession seats the entrants of a tournament at tables for its next
+// session and creates a room for each table, then starts the game at every
+// table once all the rooms have been created. If the session fails to start,
+// the rooms created so far are kept and the next attempt resumes the session
+// instead of seating the entrants again.
+func (s *tournamentService) StartSession(tournament *Tournament, playerID string) error {
+	tournament.Lock()
+	defer tournament.Unlock()
+	t := tournament
+	if playerID != t.Organiser {
+		return &Error{error: errNotOrganiser}
+	}
+	if !t.pending() {
+		if s.refresh(t) {
+			err := s.TournamentRepository.Save(t)
+			if err != nil {
+				return &Error{error: err, internal: true}
+			}
+		}
+		seatings, err := t.seatings(rand.New(rand.NewSource(rand.Int63())))
+		if err != nil {
+			return &Error{error: err}
+		}
+		t.addSession(seatings, make([]string, len(seatings)))
+		t.Sessions[len(t.Sessions)-1].Pending = true
+	}
+	startErr := s.startTables(t)
+	err := s.TournamentRepository.Save(t)
+	if startErr != nil {
+		return startErr
+	}
+	if err != nil {
+		return &Error{error: err, internal: true}
+	}
+	return nil
+}
+
+// startTables creates a room for each table in the pending session of a
+// tournament which does not have one yet, then starts the games in them. The
+// caller must hold the lock on the tournament.
+func (s *tournamentService) startTables(t *Tournament) error {
+	session := &t.Sessions[len(t.Sessions)-1]
+	settings := RoomSettings{
+		Ruleset: t.Settings.Ruleset,
+		Winds:   t.Settings.Winds,
+	}
+	for i := range session.Tables {
+		table := &session.Tables[i]
+		if table.RoomID != "" {
+			continue
+		}
+		players := make([]Player, len(table.Entrants))
+		for j, entrant := range table.Entrants {
+			players[j] = Player{
+				ID:       t.Entrants[entrant].PlayerID,
+				Name:     t.Entrants[entrant].Name,
+				Username: t.Entrants[entrant].Username,
+			}
+		}
+		room, err := s.roomService.SeatTable(players, settings, t.ID)
+		if err != nil {
+			return err
+		}
+		table.RoomID = room.ID
+	}
+	for _, table := range session.Tables {
+		room, err := s.roomService.Get(table.RoomID)
+		if err != nil {
+			return err
+		}
+		err = s.roomService.StartTable(room)
+		if err != nil {
+			return err
+		}
+	}
+	session.Pending = false
+	return nil
+}
+
+// SetTableResult records the result of a table on behalf of the organiser.
+func (s *tournamentService) SetTableResult(tournament *Tournament, playerID string, session, table int, result TableResult) error {
+	return s.update(tournament, func(t *Tournament) error {
+		return t.setTableResult(playerID, session, table, result)
+	})
+}
+
+// refresh records the result of every finished room in the latest session
+// of a tournament which has not been recorded yet, such as when recording it
+// failed as its game finished. It returns whether any tables were recorded.
+// The caller must hold the lock on the tournament.
+func (s *tournamentService) refresh(t *Tournament) bool {
+	if len(t.Sessions) == 0 || t.pending() {
+		return false
+	}
+	refreshed := false
+	for _, table := range t.Sessions[len(t.Sessions)-1].Tables {
+		if table.Finished || table.Error != "" {
+			continue
+		}
+		room, err := s.roomService.Get(table.RoomID)
+		if err != nil {
+			fmt.Printf("tournament=%s room=%s error getting room: %v\n", t.ID, table.RoomID, err)
+			continue
+		}
+		room.WithRLock(func(r *Room) {
+			if r.Phase == PhaseFinished && t.recordTable(r) == nil {
+				refreshed = true
+			}
+		})
+	}
+	return refreshed
+}
+
+// View returns a tournament's view after recording any finished tables.
+func (s *tournamentService) View(tournament *Tournament) (TournamentView, error) {
+	tournament.Lock()
+	defer tournament.Unlock()
+	if s.refresh(tournament) {
+		err := s.TournamentRepository.Save(tournament)
+		if err != nil {
+			return TournamentView{}, &Error{error: err, internal: true}
+		}
+	}
+	return tournament.view(), nil
+}
+
+// RecordTable records the final scores of a finished tournament room.
+func (s *tournamentService) RecordTable(room *Room) error {
+	tournament, err := s.Get(room.Tournament)
+	if err != nil {
+		return err
+	}
+	return s.update(tournament, func(t *Tournament) error {
+		var err error
+		room.WithRLock(func(r *Room) {
+			err = t.recordTable(r)
+		})
+		return err
+	})
+}
@@ -0,0 +1,423 @@
+package parlour
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yi-jiayu/mahjong.go"
+)
+
+func newTestTournament(entrants int, pairing Pairing, sessions int) *Tournament {
+	t := NewTournament("id0", TournamentSettings{Name: "Monthly", Ruleset: RulesetDefault, Pairing: pairing, Sessions: sessions})
+	t.ID = "T1"
+	for i := 1; i <= entrants; i++ {
+		_ = t.register(Entrant{PlayerID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("player%d", i)})
+	}
+	return t
+}
+
+// finishSession records scores for every table in the latest session of a
+// tournament, with the entrants seated first at each table doing best.
+func finishSession(t *Tournament, scores [4]int) {
+	session := &t.Sessions[len(t.Sessions)-1]
+	for i := range session.Tables {
+		session.Tables[i].Scores = scores
+		session.Tables[i].Finished = true
+	}
+	if len(t.Sessions) == t.Settings.Sessions {
+		t.Phase = TournamentFinished
+	}
+}
+
+func TestTournamentSettings_validate(t *testing.T) {
+	settings := TournamentSettings{Name: " Monthly ", Sessions: 3}
+	assert.NoError(t, settings.validate())
+	assert.Equal(t, TournamentSettings{Name: "Monthly", Ruleset: RulesetDefault, Pairing: PairingSwiss, Sessions: 3}, settings)
+
+	for _, tt := range []struct {
+		settings TournamentSettings
+		err      string
+	}{
+		{TournamentSettings{Sessions: 1}, "name is required"},
+		{TournamentSettings{Name: "Monthly", Pairing: "random", Sessions: 1}, "invalid pairing"},
+		{TournamentSettings{Name: "Monthly", Sessions: 0}, "invalid number of sessions"},
+		{TournamentSettings{Name: "Monthly", Winds: 5, Sessions: 1}, "invalid number of winds"},
+	} {
+		assert.EqualError(t, tt.settings.validate(), tt.err)
+	}
+}
+
+func TestTournament_register(t *testing.T) {
+	tournament := newTestTournament(2, PairingSwiss, 1)
+	err := tournament.register(Entrant{PlayerID: "id1", Name: "player1"})
+	assert.EqualError(t, err, "already registered")
+	err = tournament.register(Entrant{PlayerID: "id3", Name: "player1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "player1 2", tournament.Entrants[2].Name)
+
+	assert.NoError(t, tournament.withdraw("id2"))
+	assert.EqualError(t, tournament.withdraw("id2"), "not registered")
+	assert.Len(t, tournament.Entrants, 2)
+
+	tournament.Phase = TournamentInProgress
+	assert.Equal(t, errRegistrationClosed, tournament.register(Entrant{PlayerID: "id4"}))
+}
+
+func TestTournament_seatings(t *testing.T) {
+	t.Run("multiple of four entrants", func(t *testing.T) {
+		tournament := newTestTournament(6, PairingSwiss, 1)
+		_, err := tournament.seatings(rand.New(rand.NewSource(0)))
+		assert.EqualError(t, err, "number of entrants must be a multiple of four")
+	})
+	t.Run("previous session unfinished", func(t *testing.T) {
+		tournament := newTestTournament(4, PairingSwiss, 2)
+		tournament.addSession([][4]int{{0, 1, 2, 3}}, []string{"ABCD"})
+		_, err := tournament.seatings(rand.New(rand.NewSource(0)))
+		assert.EqualError(t, err, "session in progress")
+	})
+	t.Run("no more sessions", func(t *testing.T) {
+		tournament := newTestTournament(4, PairingSwiss, 1)
+		tournament.addSession([][4]int{{0, 1, 2, 3}}, []string{"ABCD"})
+		finishSession(tournament, [4]int{10, 0, 0, -10})
+		_, err := tournament.seatings(rand.New(rand.NewSource(0)))
+		assert.EqualError(t, err, "no more sessions")
+	})
+	t.Run("rotation avoids repeat opponents", func(t *testing.T) {
+		for _, entrants := range []int{16, 20} {
+			met := playRotation(t, entrants, 5)
+			for pair, count := range met {
+				assert.Equal(t, 1, count, "%d entrants: entrants %d and %d met %d times", entrants, pair[0], pair[1], count)
+			}
+		}
+	})
+	t.Run("rotation with every pair meeting", func(t *testing.T) {
+		met := playRotation(t, 16, 5)
+		assert.Len(t, met, 16*15/2)
+	})
+	t.Run("rotation spreads out unavoidable repeats", func(t *testing.T) {
+		// with eight entrants, each table after the first session must seat
+		// at least two entrants who have already met
+		met := playRotation(t, 8, 4)
+		for pair, count := range met {
+			assert.LessOrEqual(t, count, 2, "entrants %d and %d met %d times", pair[0], pair[1], count)
+		}
+	})
+	t.Run("swiss groups entrants by standings", func(t *testing.T) {
+		tournament := newTestTournament(8, PairingSwiss, 2)
+		seatings, err := tournament.seatings(rand.New(rand.NewSource(0)))
+		assert.NoError(t, err)
+		tournament.addSession(seatings, []string{"ABCD", "EFGH"})
+		finishSession(tournament, [4]int{30, 10, -10, -30})
+
+		seatings, err = tournament.seatings(rand.New(rand.NewSource(0)))
+		assert.NoError(t, err)
+		winners := []int{seatings[0][0], seatings[0][1]}
+		assert.ElementsMatch(t, []int{tournament.Sessions[0].Tables[0].Entrants[0], tournament.Sessions[0].Tables[1].Entrants[0]}, winners)
+	})
+}
+
+// playRotation seats entrants for a number of sessions with rotation pairing
+// and returns the number of times each pair of entrants met.
+func playRotation(t *testing.T, entrants, sessions int) map[[2]int]int {
+	tournament := newTestTournament(entrants, PairingRotation, sessions)
+	met := make(map[[2]int]int)
+	for session := 0; session < sessions; session++ {
+		seatings, err := tournament.seatings(nil)
+		if !assert.NoError(t, err) {
+			return met
+		}
+		seated := make(map[int]bool)
+		for _, table := range seatings {
+			for i, a := range table {
+				seated[a] = true
+				for _, b := range table[i+1:] {
+					pair := [2]int{a, b}
+					if b < a {
+						pair = [2]int{b, a}
+					}
+					met[pair]++
+				}
+			}
+		}
+		assert.Len(t, seated, entrants)
+		tournament.addSession(seatings, make([]string, len(seatings)))
+		finishSession(tournament, [4]int{})
+	}
+	return met
+}
+
+func TestTournament_recordTable(t *testing.T) {
+	t.Run("substitute", func(t *testing.T) {
+		tournament := newTestTournament(4, PairingSwiss, 1)
+		tournament.addSession([][4]int{{0, 1, 2, 3}}, []string{"ABCD"})
+		r := newFullRoom()
+		r.ID = "ABCD"
+		r.Phase = PhaseFinished
+		r.Players[1].Vacated = true
+		r.Players[1].Substitute = botNames[0]
+		r.Scores = [4]int{10, 20, -10, -20}
+		assert.NoError(t, tournament.recordTable(r))
+		assert.Equal(t, [4]int{10, 20, -10, -20}, tournament.Sessions[0].Tables[0].Scores)
+		assert.Equal(t, TournamentFinished, tournament.Phase)
+	})
+	t.Run("entrant not seated", func(t *testing.T) {
+		tournament := newTestTournament(4, PairingSwiss, 1)
+		tournament.addSession([][4]int{{0, 1, 2, 3}}, []string{"ABCD"})
+		r := newFullRoom()
+		r.ID = "ABCD"
+		r.Phase = PhaseFinished
+		r.Players[1] = Player{ID: botNames[0], Name: botNames[0], IsBot: true}
+		assert.NoError(t, tournament.recordTable(r))
+		assert.False(t, tournament.Sessions[0].Tables[0].Finished)
+		assert.Equal(t, "entrant id2 not seated in room ABCD", tournament.Sessions[0].Tables[0].Error)
+
+		assert.NoError(t, tournament.setTableResult("id0", 0, 0, TableResult{Scores: [4]int{10, -10}}))
+		assert.True(t, tournament.Sessions[0].Tables[0].Finished)
+		assert.Empty(t, tournament.Sessions[0].Tables[0].Error)
+	})
+	t.Run("game not finished", func(t *testing.T) {
+		tournament := newTestTournament(4, PairingSwiss, 1)
+		tournament.addSession([][4]int{{0, 1, 2, 3}}, []string{"ABCD"})
+		r := newFullRoom()
+		r.ID = "ABCD"
+		assert.EqualError(t, tournament.recordTable(r), "game not finished")
+	})
+}
+
+func TestTournament_setTableResult(t *testing.T) {
+	tournament := newTestTournament(4, PairingSwiss, 1)
+	tournament.addSession([][4]int{{0, 1, 2, 3}}, []string{"ABCD"})
+	result := TableResult{Scores: [4]int{30, 10, -10, -30}}
+	assert.Equal(t, errNotOrganiser, tournament.setTableResult("id1", 0, 0, result))
+	assert.EqualError(t, tournament.setTableResult("id0", 1, 0, result), "invalid session")
+	assert.EqualError(t, tournament.setTableResult("id0", 0, 1, result), "invalid table")
+	assert.EqualError(t, tournament.setTableResult("id0", 0, 0, TableResult{Scores: [4]int{10}}), "scores must add up to zero")
+
+	assert.NoError(t, tournament.setTableResult("id0", 0, 0, result))
+	assert.Equal(t, TournamentTable{RoomID: "ABCD", Entrants: [4]int{0, 1, 2, 3}, Scores: result.Scores, Finished: true}, tournament.Sessions[0].Tables[0])
+	assert.Equal(t, TournamentFinished, tournament.Phase)
+	assert.Equal(t, "player1", tournament.standings()[0].Entrant.Name)
+}
+
+func TestTournament_standings(t *testing.T) {
+	tournament := newTestTournament(8, PairingSwiss, 2)
+	tournament.addSession([][4]int{{0, 1, 2, 3}, {4, 5, 6, 7}}, []string{"ABCD", "EFGH"})
+	tournament.Sessions[0].Tables[0].Scores = [4]int{20, 20, -10, -30}
+	tournament.Sessions[0].Tables[0].Finished = true
+	tournament.Sessions[0].Tables[1].Scores = [4]int{40, 0, -10, -30}
+	tournament.Sessions[0].Tables[1].Finished = true
+
+	standings := tournament.standings()
+	var names []string
+	var ranks []int
+	for _, standing := range standings {
+		names = append(names, standing.Entrant.Name)
+		ranks = append(ranks, standing.Rank)
+	}
+	// player1 and player2 tie for first at their table and share a rank
+	// behind player5, who scored more
+	assert.Equal(t, []string{"player5", "player1", "player2", "player6", "player3", "player7", "player4", "player8"}, names)
+	assert.Equal(t, []int{1, 2, 2, 4, 5, 5, 7, 7}, ranks)
+	assert.Equal(t, Standing{Rank: 1, Entrant: tournament.Entrants[4], Points: 3, Score: 40, Wins: 1, Tables: 1}, standings[0])
+	assert.Equal(t, 3, standings[1].Points)
+	assert.Equal(t, 1, standings[1].Wins)
+}
+
+// finishGame ends the game in a tournament room through the room service
+// with the given final scores.
+func finishGame(t *testing.T, s *roomService, room *Room, scores [4]int) {
+	room.WithLock(func(r *Room) {
+		r.Winds = 1
+		r.Round.Dealer = 3
+		r.Round.Finished = true
+		r.Round.Result = &mahjong.Result{Winner: -1, Loser: -1, Liable: -1}
+		r.Round.Scores = scores
+	})
+	err := s.Dispatch(room, room.Players[0].ID, Action{Nonce: room.Nonce, Type: ActionNextRound})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_tournamentService(t *testing.T) {
+	s := newRoomService(NewInMemoryRoomRepository())
+	tournaments := newTournamentService(NewInMemoryTournamentRepository(), s)
+	s.tournamentService = tournaments
+
+	tournament, err := tournaments.Create("id1", TournamentSettings{Name: "Monthly", Sessions: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 1; i <= 4; i++ {
+		err := tournaments.Register(tournament, Entrant{PlayerID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("player%d", i)})
+		assert.NoError(t, err)
+	}
+	assert.True(t, errors.Is(tournaments.StartSession(tournament, "id2"), errNotOrganiser))
+	err = tournaments.StartSession(tournament, "id1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, TournamentInProgress, tournament.Phase)
+	table := tournament.Sessions[0].Tables[0]
+	room, err := s.Get(table.RoomID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, tournament.ID, room.Tournament)
+	assert.Equal(t, PhaseInProgress, room.Phase)
+
+	var scores [4]int
+	for i, player := range room.Players {
+		scores[i] = map[string]int{"id1": 30, "id2": 10, "id3": -10, "id4": -30}[player.ID]
+	}
+	finishGame(t, s, room, scores)
+	assert.Equal(t, TournamentFinished, tournament.Phase)
+	standings := tournament.standings()
+	assert.Equal(t, "player1", standings[0].Entrant.Name)
+	assert.Equal(t, 30, standings[0].Score)
+	assert.Equal(t, "player4", standings[3].Entrant.Name)
+}
+
+// flakyRoomRepository fails to save rooms once it has saved a number of them.
+type flakyRoomRepository struct {
+	RoomRepository
+	saves int
+}
+
+func (r *flakyRoomRepository) Save(room *Room) error {
+	if r.saves == 0 {
+		return errors.New("connection lost")
+	}
+	r.saves--
+	return r.RoomRepository.Save(room)
+}
+
+func Test_tournamentService_StartSession(t *testing.T) {
+	repo := &flakyRoomRepository{RoomRepository: NewInMemoryRoomRepository(), saves: 2}
+	s := newRoomService(repo)
+	tournaments := newTournamentService(NewInMemoryTournamentRepository(), s)
+	tournament, _ := tournaments.Create("id0", TournamentSettings{Name: "Monthly", Sessions: 1})
+	for i := 1; i <= 8; i++ {
+		_ = tournaments.Register(tournament, Entrant{PlayerID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("player%d", i)})
+	}
+
+	// the first table's room is created before the second one fails to be
+	err := tournaments.StartSession(tournament, "id0")
+	assert.Error(t, err)
+	if !assert.Len(t, tournament.Sessions, 1) {
+		return
+	}
+	session := tournament.Sessions[0]
+	assert.True(t, session.Pending)
+	assert.NotEmpty(t, session.Tables[0].RoomID)
+	assert.Empty(t, session.Tables[1].RoomID)
+	room, _ := s.Get(session.Tables[0].RoomID)
+	assert.Equal(t, PhaseLobby, room.Phase, "games should not start until every room is created")
+
+	repo.saves = 10
+	err = tournaments.StartSession(tournament, "id0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, tournament.Sessions, 1)
+	assert.False(t, tournament.Sessions[0].Pending)
+	assert.Equal(t, session.Tables[0].RoomID, tournament.Sessions[0].Tables[0].RoomID)
+	assert.Equal(t, session.Tables[0].Entrants, tournament.Sessions[0].Tables[0].Entrants)
+	for _, table := range tournament.Sessions[0].Tables {
+		room, err := s.Get(table.RoomID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, PhaseInProgress, room.Phase)
+		}
+	}
+}
+
+func Test_tournamentService_View(t *testing.T) {
+	s := newRoomService(NewInMemoryRoomRepository())
+	tournaments := newTournamentService(NewInMemoryTournamentRepository(), s)
+	tournament, _ := tournaments.Create("id1", TournamentSettings{Name: "Monthly", Sessions: 1})
+	for i := 1; i <= 4; i++ {
+		_ = tournaments.Register(tournament, Entrant{PlayerID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("player%d", i)})
+	}
+	if !assert.NoError(t, tournaments.StartSession(tournament, "id1")) {
+		return
+	}
+	room, _ := s.Get(tournament.Sessions[0].Tables[0].RoomID)
+
+	// the room service is not linked to the tournament service, so the table
+	// is not recorded as the game finishes
+	finishGame(t, s, room, [4]int{30, 10, -10, -30})
+	assert.False(t, tournament.Sessions[0].Tables[0].Finished)
+
+	view, err := tournaments.View(tournament)
+	assert.NoError(t, err)
+	assert.True(t, view.Sessions[0].Tables[0].Finished)
+	assert.Equal(t, TournamentFinished, view.Phase)
+}
+
+func TestParlour_tournaments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	parlour := New(NewInMemoryRoomRepository(), memstore.NewStore([]byte("secret")))
+	parlour.configure(router)
+	tokens := make([]string, 5)
+	for i := 1; i <= 4; i++ {
+		_, tokens[i], _ = parlour.tokenService.Create(fmt.Sprintf("id%d", i), "")
+	}
+
+	do := func(player int, method, path, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokens[player])
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(1, http.MethodPost, "/tournaments", "application/json", `{"name": "Monthly", "pairing": "rotation", "sessions": 2}`)
+	if !assert.Equal(t, http.StatusCreated, w.Code) {
+		return
+	}
+	var view TournamentView
+	_ = json.Unmarshal(w.Body.Bytes(), &view)
+	path := "/tournaments/" + view.ID
+
+	for i := 1; i <= 4; i++ {
+		w = do(i, http.MethodPost, path+"/entrants", "application/x-www-form-urlencoded", fmt.Sprintf("name=player%d", i))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+	assert.Equal(t, http.StatusBadRequest, do(2, http.MethodPost, path+"/sessions", "", "").Code)
+	assert.Equal(t, http.StatusNoContent, do(1, http.MethodPost, path+"/sessions", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(1, http.MethodPost, path+"/sessions", "", "").Code)
+
+	w = do(3, http.MethodGet, path, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &view)
+	assert.Equal(t, TournamentInProgress, view.Phase)
+	assert.Len(t, view.Entrants, 4)
+	assert.Len(t, view.Standings, 4)
+	if assert.Len(t, view.Sessions, 1) {
+		assert.Len(t, view.Sessions[0].Tables, 1)
+		assert.NotEmpty(t, view.Sessions[0].Tables[0].RoomID)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do(2, http.MethodPut, path+"/sessions/0/tables/0", "application/json", `{"scores": [30, 10, -10, -30]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(1, http.MethodPut, path+"/sessions/x/tables/0", "application/json", `{"scores": [30, 10, -10, -30]}`).Code)
+	assert.Equal(t, http.StatusNoContent, do(1, http.MethodPut, path+"/sessions/0/tables/0", "application/json", `{"scores": [30, 10, -10, -30]}`).Code)
+	w = do(3, http.MethodGet, path, "", "")
+	_ = json.Unmarshal(w.Body.Bytes(), &view)
+	assert.True(t, view.Sessions[0].Tables[0].Finished)
+
+	assert.Equal(t, http.StatusNotFound, do(1, http.MethodGet, "/tournaments/missing", "", "").Code)
+}
@@ -1,6 +1,7 @@
 package mahjong
 
 import (
+	"encoding/json"
 	"errors"
 	"math/rand"
 	"sort"
@@ -26,8 +27,9 @@ type Round struct {
 	// Wall contains the remaining tiles left to be drawn.
 	Wall []Tile
 
-	// Discards contains all the previously discarded tiles.
-	Discards []Tile
+	// Rivers contains the tiles discarded by each player in the order they
+	// were discarded.
+	Rivers [4][]DiscardedTile
 
 	// Wind is the prevailing wind for the round.
 	Wind Direction
@@ -44,6 +46,9 @@ type Round struct {
 	// Events contains all the events that happened in the round.
 	Events []Event
 
+	// ScoreEvents contains all the immediate payments made during the round.
+	ScoreEvents []ScoreEvent
+
 	// Result is the outcome of the round.
 	Result *Result
 
@@ -57,21 +62,129 @@ type Round struct {
 	// precedence can hu after someone else has already done so.
 	WinningTile Tile
 
+	// RobbableTile is the tile added to a pong to form a gang, which other
+	// players may win on during PhaseRobGang.
+	RobbableTile Tile
+
+	// Context describes how the most recently drawn or discarded tile came
+	// about, for scoring a win on that tile.
+	Context WinningContext
+
 	LastActionTime   time.Time
 	ReservedDuration time.Duration
 }
 
+// DiscardedTile represents a tile in a player's discard river.
+type DiscardedTile struct {
+	// Tile is the tile which was discarded.
+	Tile Tile `json:"tile"`
+
+	// Time is the time a tile was discarded.
+	Time int64 `json:"time"`
+
+	// Claimed indicates whether a tile was claimed by another player.
+	Claimed bool `json:"claimed"`
+
+	// ClaimedBy is the integer offset of the player who claimed a tile. It is
+	// only meaningful if Claimed is true.
+	ClaimedBy int `json:"claimed_by"`
+}
+
+// lastDiscard returns the most recently discarded tile, or an empty tile if
+// it has already been claimed.
 func (r *Round) lastDiscard() Tile {
-	if len(r.Discards) == 0 {
+	river := r.Rivers[r.previousTurn()]
+	if len(river) == 0 || river[len(river)-1].Claimed {
 		return ""
 	}
-	return r.Discards[len(r.Discards)-1]
+	return river[len(river)-1].Tile
+}
+
+// claimLastDiscard marks the most recently discarded tile as claimed by seat
+// and returns it.
+func (r *Round) claimLastDiscard(seat int) Tile {
+	river := r.Rivers[r.previousTurn()]
+	discard := &river[len(river)-1]
+	discard.Claimed = true
+	discard.ClaimedBy = seat
+	return discard.Tile
 }
 
-func (r *Round) popLastDiscard() Tile {
-	tile := r.Discards[len(r.Discards)-1]
-	r.Discards = r.Discards[:len(r.Discards)-1]
-	return tile
+// unclaimedDiscards returns all the discarded tiles which were not claimed in
+// the order they were discarded.
+func (r *Round) unclaimedDiscards() []Tile {
+	tiles := []Tile{}
+	var next [4]int
+	for _, event := range r.Events {
+		if event.Type != EventDiscard {
+			continue
+		}
+		river := r.Rivers[event.Seat]
+		if next[event.Seat] >= len(river) {
+			continue
+		}
+		discard := river[next[event.Seat]]
+		next[event.Seat]++
+		if !discard.Claimed {
+			tiles = append(tiles, discard.Tile)
+		}
+	}
+	return tiles
+}
+
+// UnmarshalJSON decodes a round. Rounds saved before discards were kept per
+// seat only have a single list of unclaimed discards, so their rivers are
+// rebuilt from the round's events instead.
+func (r *Round) UnmarshalJSON(data []byte) error {
+	type round Round
+	var legacy struct {
+		*round
+		Discards []Tile
+	}
+	legacy.round = (*round)(r)
+	err := json.Unmarshal(data, &legacy)
+	if err != nil {
+		return err
+	}
+	if legacy.Discards != nil && r.Rivers[0] == nil && r.Rivers[1] == nil && r.Rivers[2] == nil && r.Rivers[3] == nil {
+		r.Rivers = riversFromEvents(r.Events)
+	}
+	return nil
+}
+
+// riversFromEvents rebuilds each seat's discards from the events in a round.
+// A discard is claimed by whoever chows, pongs, gangs or wins on it
+// immediately afterwards.
+func riversFromEvents(events []Event) [4][]DiscardedTile {
+	rivers := [4][]DiscardedTile{{}, {}, {}, {}}
+	var last *DiscardedTile
+	for _, event := range events {
+		switch event.Type {
+		case EventDiscard:
+			if len(event.Tiles) == 0 {
+				continue
+			}
+			seat := event.Seat
+			rivers[seat] = append(rivers[seat], DiscardedTile{
+				Tile: event.Tiles[0],
+				Time: event.Time,
+			})
+			last = &rivers[seat][len(rivers[seat])-1]
+			continue
+		case EventChi, EventPong, EventGang, EventHu:
+			if last != nil {
+				last.Claimed = true
+				last.ClaimedBy = event.Seat
+			}
+			if event.Type == EventHu {
+				// a player with higher precedence may still win on the
+				// same discard
+				continue
+			}
+		}
+		last = nil
+	}
+	return rivers
 }
 
 func (r *Round) drawFront() Tile {
@@ -96,12 +209,39 @@ func (r *Round) previousTurn() int {
 	return (r.Turn + 3) % 4
 }
 
+// WinningContext describes how a tile arrived, for scoring a win on it.
+type WinningContext struct {
+	// GangReplacement indicates that a tile was drawn to replace a gang.
+	GangReplacement bool `json:"gang_replacement,omitempty"`
+
+	// FlowerReplacement indicates that a tile was drawn to replace a flower.
+	FlowerReplacement bool `json:"flower_replacement,omitempty"`
+
+	// LastTile indicates that a tile was the last tile drawn from the wall.
+	LastTile bool `json:"last_tile,omitempty"`
+
+	// LastDiscard indicates that a tile was discarded with only one draw
+	// left in the wall.
+	LastDiscard bool `json:"last_discard,omitempty"`
+
+	// FirstTurn indicates that no tiles have been drawn or claimed since the
+	// round started apart from the dealer's first discard.
+	FirstTurn bool `json:"first_turn,omitempty"`
+}
+
+func (r *Round) drawsLeft() int {
+	return len(r.Wall) - MinTilesLeft + 1
+}
+
 func (r *Round) replaceTile(seat int, t time.Time) {
+	r.Context = WinningContext{GangReplacement: true}
 	drawn := r.drawBack()
 	for isFlower(drawn) {
 		r.addFlower(seat, t, drawn)
+		r.Context.FlowerReplacement = true
 		drawn = r.drawBack()
 	}
+	r.Context.LastTile = r.drawsLeft() <= 0
 	r.Hands[seat].Concealed.Add(drawn)
 }
 
@@ -113,22 +253,37 @@ func (r *Round) Draw(seat int, t time.Time) error {
 	if r.Turn != seat {
 		return errors.New("wrong turn")
 	}
-	if r.Phase != PhaseDraw {
+	if r.Phase != PhaseDraw && r.Phase != PhaseRobGang {
 		return errors.New("wrong phase")
 	}
 	if t.Before(r.LastActionTime.Add(r.ReservedDuration)) {
 		return errors.New("cannot draw during reserved duration")
 	}
+	if r.Phase == PhaseRobGang {
+		// nobody robbed the gang, so draw its replacement tile
+		if r.Finished {
+			return errors.New("round finished")
+		}
+		r.replaceTile(seat, t)
+		r.payAll(ScoreExposedGang, seat, r.Rules.ExposedGangPayout, t)
+		r.RobbableTile = ""
+		r.Phase = PhaseDiscard
+		r.LastActionTime = t
+		return nil
+	}
 	r.Events = append(r.Events, Event{
 		Type: EventDraw,
 		Seat: seat,
 		Time: timeInMillis(t),
 	})
+	r.Context = WinningContext{}
 	drawn := r.drawFront()
 	for isFlower(drawn) {
 		r.addFlower(seat, t, drawn)
+		r.Context.FlowerReplacement = true
 		drawn = r.drawBack()
 	}
+	r.Context.LastTile = r.drawsLeft() <= 0
 	hand := &r.Hands[seat]
 	hand.Concealed.Add(drawn)
 	r.Phase = PhaseDiscard
@@ -150,7 +305,14 @@ func (r *Round) Discard(seat int, t time.Time, tile Tile) error {
 		return errors.New("no draws left")
 	}
 	r.Hands[seat].Concealed.Remove(tile)
-	r.Discards = append(r.Discards, tile)
+	r.Context = WinningContext{
+		LastDiscard: r.drawsLeft() == 1,
+		FirstTurn:   r.Context.FirstTurn && seat == r.Dealer,
+	}
+	r.Rivers[seat] = append(r.Rivers[seat], DiscardedTile{
+		Tile: tile,
+		Time: timeInMillis(t),
+	})
 	r.Turn = (r.Turn + 1) % 4
 	r.Phase = PhaseDraw
 	r.Events = append(r.Events, newEvent(EventDiscard, seat, t, tile))
@@ -168,10 +330,10 @@ func (r *Round) Chi(seat int, t time.Time, tile1, tile2 Tile) error {
 	if r.Phase != PhaseDraw {
 		return errors.New("wrong phase")
 	}
-	if len(r.Discards) == 0 {
+	tile0 := r.lastDiscard()
+	if tile0 == "" {
 		return errors.New("no discards")
 	}
-	tile0 := r.lastDiscard()
 	if !isValidSequence(tile0, tile1, tile2) {
 		return errors.New("invalid sequence")
 
@@ -185,7 +347,8 @@ func (r *Round) Chi(seat int, t time.Time, tile1, tile2 Tile) error {
 	}
 	hand.Concealed.Remove(tile1)
 	hand.Concealed.Remove(tile2)
-	r.popLastDiscard()
+	r.claimLastDiscard(seat)
+	r.Context = WinningContext{}
 	seq := []Tile{tile0, tile1, tile2}
 	sort.Slice(seq, func(i, j int) bool {
 		return seq[i] < seq[j]
@@ -193,6 +356,7 @@ func (r *Round) Chi(seat int, t time.Time, tile1, tile2 Tile) error {
 	hand.Revealed = append(hand.Revealed, Meld{
 		Type:  MeldChi,
 		Tiles: seq,
+		From:  r.previousTurn(),
 	})
 	r.Phase = PhaseDiscard
 	r.Events = append(r.Events, newEvent(EventChi, seat, t, seq...))
@@ -210,18 +374,21 @@ func (r *Round) Pong(seat int, t time.Time) error {
 	if r.Phase != PhaseDraw {
 		return errors.New("wrong phase")
 	}
-	if len(r.Discards) == 0 {
+	if r.lastDiscard() == "" {
 		return errors.New("no discards")
 	}
 	hand := &r.Hands[seat]
 	if hand.Concealed.Count(r.lastDiscard()) < 2 {
 		return errors.New("missing tiles")
 	}
-	tile := r.popLastDiscard()
+	from := r.previousTurn()
+	tile := r.claimLastDiscard(seat)
+	r.Context = WinningContext{}
 	hand.Concealed.RemoveN(tile, 2)
 	hand.Revealed = append(hand.Revealed, Meld{
 		Type:  MeldPong,
 		Tiles: []Tile{tile},
+		From:  from,
 	})
 	r.Events = append(r.Events, newEvent(EventPong, seat, t, tile))
 	r.Turn = seat
@@ -240,21 +407,26 @@ func (r *Round) GangFromDiscard(seat int, t time.Time) error {
 	if r.Phase != PhaseDraw {
 		return errors.New("wrong phase")
 	}
-	if len(r.Discards) == 0 {
+	if r.lastDiscard() == "" {
 		return errors.New("no discards")
 	}
 	hand := &r.Hands[seat]
 	if hand.Concealed.Count(r.lastDiscard()) < 3 {
 		return errors.New("missing tiles")
 	}
-	tile := r.popLastDiscard()
+	from := r.previousTurn()
+	tile := r.claimLastDiscard(seat)
 	hand.Concealed.RemoveN(tile, 3)
 	hand.Revealed = append(hand.Revealed, Meld{
 		Type:  MeldGang,
 		Tiles: []Tile{tile},
+		From:  from,
 	})
 	r.replaceTile(seat, t)
 	r.Events = append(r.Events, newEvent(EventGang, seat, t, tile))
+	if r.Rules.ExposedGangPayout != 0 {
+		r.pay(ScoreExposedGang, from, seat, 3*r.Rules.ExposedGangPayout, t)
+	}
 	r.Turn = seat
 	r.Phase = PhaseDiscard
 	r.LastActionTime = t
@@ -275,19 +447,25 @@ func (r *Round) GangFromHand(seat int, t time.Time, tile Tile) error {
 	if hand.Concealed.Count(tile) > 3 {
 		hand.Concealed.RemoveN(tile, 4)
 		hand.Revealed = append(hand.Revealed, Meld{
-			Type:  MeldGang,
-			Tiles: []Tile{tile},
+			Type:      MeldGang,
+			Tiles:     []Tile{tile},
+			Concealed: true,
+			From:      -1,
 		})
 		r.replaceTile(seat, t)
-		r.Events = append(r.Events, newEvent(EventGang, seat, t, tile))
+		r.Events = append(r.Events, newEvent(EventConcealedGang, seat, t, tile))
+		r.payAll(ScoreConcealedGang, seat, r.Rules.ConcealedGangPayout, t)
 		r.LastActionTime = t
 		return nil
 	}
 	for i, meld := range hand.Revealed {
 		if meld.Type == MeldPong && meld.Tiles[0] == tile && hand.Concealed.Count(tile) > 0 {
+			// the replacement tile is only drawn after other players have had
+			// a chance to rob the gang
 			hand.Concealed.Remove(tile)
 			hand.Revealed[i].Type = MeldGang
-			r.replaceTile(seat, t)
+			r.RobbableTile = tile
+			r.Phase = PhaseRobGang
 			r.Events = append(r.Events, newEvent(EventGang, seat, t, tile))
 			r.LastActionTime = t
 			return nil
@@ -296,9 +474,9 @@ func (r *Round) GangFromHand(seat int, t time.Time, tile Tile) error {
 	return errors.New("missing tiles")
 }
 
-func bestHand(winningHands []Melds, round *Round, seat int) (Melds, int) {
+func bestHand(winningHands []Melds, round *Round, seat int) (Melds, []Tai) {
 	melds := append(round.Hands[seat].Revealed, winningHands[0]...)
-	return winningHands[0], score(round, seat, melds)
+	return winningHands[0], tally(round, seat, melds)
 }
 
 func winningTiles(flowers []Tile, melds Melds, rest Melds) []Tile {
@@ -316,7 +494,7 @@ func removeTile(tiles []Tile, tile Tile) []Tile {
 	return tiles
 }
 
-func (r *Round) tsumo(seat int) (best Melds, points int, err error) {
+func (r *Round) tsumo(seat int) (best Melds, tai []Tai, err error) {
 	if r.Finished {
 		err = errors.New("already won")
 		return
@@ -326,16 +504,32 @@ func (r *Round) tsumo(seat int) (best Melds, points int, err error) {
 		err = errors.New("missing tiles")
 		return
 	}
-	best, points = bestHand(winningHands, r, seat)
-	if points == 0 {
+	best, tai = bestHand(winningHands, r, seat)
+	if sumTai(tai) == 0 {
 		err = errors.New("no tai")
 		return
 	}
 	return
 }
 
-func (r *Round) ron(seat int, t time.Time) (best Melds, points, loser int, err error) {
+// robGang reverts the gang being robbed back to a pong and returns the tile
+// which was added to it.
+func (r *Round) robGang() Tile {
+	hand := &r.Hands[r.Turn]
+	for i, meld := range hand.Revealed {
+		if meld.Type == MeldGang && meld.Tiles[0] == r.RobbableTile {
+			hand.Revealed[i].Type = MeldPong
+			break
+		}
+	}
+	return r.RobbableTile
+}
+
+func (r *Round) ron(seat int, t time.Time) (best Melds, tai []Tai, loser int, err error) {
 	loser = r.previousTurn()
+	if r.Phase == PhaseRobGang {
+		loser = r.Turn
+	}
 	if r.Finished {
 		if t.After(r.LastActionTime.Add(r.ReservedDuration)) {
 			err = errors.New("too late")
@@ -351,6 +545,8 @@ func (r *Round) ron(seat int, t time.Time) (best Melds, points, loser int, err e
 	var winningTile Tile
 	if r.WinningTile != "" {
 		winningTile = r.WinningTile
+	} else if r.Phase == PhaseRobGang {
+		winningTile = r.RobbableTile
 	} else {
 		winningTile = r.lastDiscard()
 	}
@@ -359,45 +555,67 @@ func (r *Round) ron(seat int, t time.Time) (best Melds, points, loser int, err e
 		err = errors.New("missing tiles")
 		return
 	}
-	best, points = bestHand(winningHands, r, seat)
-	if points == 0 {
+	best, tai = bestHand(winningHands, r, seat)
+	if sumTai(tai) == 0 {
 		err = errors.New("no tai")
 		return
 	}
 	if !r.Finished {
-		// take the winning tile from the discard pile
-		r.WinningTile = r.popLastDiscard()
+		if r.Phase == PhaseRobGang {
+			// take the winning tile from the gang
+			r.WinningTile = r.robGang()
+		} else {
+			// take the winning tile from the discard pile
+			r.WinningTile = r.claimLastDiscard(seat)
+		}
 	} else {
 		// take it from the previous winner
 		r.Hands[r.Result.Winner].Finished = removeTile(r.Hands[r.Result.Winner].Finished, r.WinningTile)
+		if r.Phase != PhaseRobGang {
+			// the discard was already claimed by the previous winner
+			river := r.Rivers[loser]
+			river[len(river)-1].ClaimedBy = seat
+		}
 	}
 	return
 }
 
 func (r *Round) Hu(seat int, t time.Time) error {
-	if seat == r.previousTurn() {
-		return errors.New("wrong turn")
-	}
-	if r.Turn != seat && r.Phase == PhaseDiscard {
-		return errors.New("wrong turn")
+	if r.Phase == PhaseRobGang {
+		if seat == r.Turn {
+			return errors.New("wrong turn")
+		}
+	} else {
+		if seat == r.previousTurn() {
+			return errors.New("wrong turn")
+		}
+		if r.Turn != seat && r.Phase == PhaseDiscard {
+			return errors.New("wrong turn")
+		}
 	}
 	var best Melds
-	var points, loser int
+	var tai []Tai
+	var loser int
 	var err error
 	if r.Phase == PhaseDiscard {
 		loser = -1
-		best, points, err = r.tsumo(seat)
+		best, tai, err = r.tsumo(seat)
 	} else {
-		best, points, loser, err = r.ron(seat, t)
+		best, tai, loser, err = r.ron(seat, t)
 	}
 	if err != nil {
 		return err
 	}
+	points := sumTai(tai)
+	liable := -1
+	if r.Rules.Bao {
+		liable = liability(r.Hands[seat].Revealed, best, r.WinningTile, loser)
+	}
 	r.Hands[seat].Concealed = TileBag{}
 	r.Hands[seat].Finished = best.Tiles()
 	// undo previous score distribution if someone won previously
 	if r.Result != nil {
-		for i, delta := range winnings(r.Rules, r.Result.Winner, r.Result.Loser, r.Result.Points) {
+		for i, delta := range r.Result.Payments {
 			r.Scores[i] -= delta
 		}
 	}
@@ -408,10 +626,13 @@ func (r *Round) Hu(seat int, t time.Time) error {
 		WinningTiles: winningTiles(r.Hands[seat].Flowers, r.Hands[seat].Revealed, best),
 		Loser:        loser,
 		Points:       points,
+		Breakdown:    tai,
+		Liable:       liable,
+		Payments:     payments(r.Rules, seat, loser, liable, points),
 	}
 	r.LastActionTime = t
 	r.Events = append(r.Events, newEvent(EventHu, seat, t))
-	for i, delta := range winnings(r.Rules, seat, loser, points) {
+	for i, delta := range r.Result.Payments {
 		r.Scores[i] += delta
 	}
 	r.Finished = true
@@ -523,15 +744,41 @@ func (r *Round) addFlower(seat int, t time.Time, flower Tile) {
 					Time:  timeInMillis(t),
 					Tiles: group.Flowers,
 				})
-				for i := range r.Scores {
-					if i != seat {
-						r.Scores[i] -= group.Payout
-					}
-				}
-				r.Scores[seat] += 3 * group.Payout
+				r.payAll(ScoreBitten, seat, group.Payout, t)
 			}
 		}
 	}
+	if r.Rules.FlowerSetPayout > 0 {
+		flowers := NewTileBag(r.Hands[seat].Flowers)
+		if (contains(flowerTiles, flower) && isFlowerSet(flowers)) || (contains(seasonTiles, flower) && isSeasonSet(flowers)) {
+			r.payAll(ScoreFlowerSet, seat, r.Rules.FlowerSetPayout, t)
+		}
+	}
+}
+
+// pay records an immediate payment from payer to payee.
+func (r *Round) pay(reason ScoreReason, payer, payee, amount int, t time.Time) {
+	r.ScoreEvents = append(r.ScoreEvents, ScoreEvent{
+		Reason: reason,
+		Payer:  payer,
+		Payee:  payee,
+		Amount: amount,
+		Time:   timeInMillis(t),
+	})
+	r.Scores[payer] -= amount
+	r.Scores[payee] += amount
+}
+
+// payAll records an immediate payment from every other player to payee.
+func (r *Round) payAll(reason ScoreReason, payee, amount int, t time.Time) {
+	if amount == 0 {
+		return
+	}
+	for i := range r.Scores {
+		if i != payee {
+			r.pay(reason, i, payee, amount, t)
+		}
+	}
 }
 
 func (r *Round) Start(seed int64, t time.Time) {
@@ -539,11 +786,38 @@ func (r *Round) Start(seed int64, t time.Time) {
 	r.distributeTiles()
 	r.Turn = r.Dealer
 	r.Phase = PhaseDiscard
-	r.Discards = []Tile{}
+	r.Rivers = [4][]DiscardedTile{{}, {}, {}, {}}
+	r.Context = WinningContext{FirstTurn: true}
 	r.LastActionTime = t
 	r.Events = []Event{newEvent(EventStart, 0, t)}
 }
 
+// Void cancels a round, undoing the winnings and all immediate payments made
+// during it. A voided round ends without a winner.
+func (r *Round) Void(t time.Time) {
+	for _, event := range r.ScoreEvents {
+		r.Scores[event.Payer] += event.Amount
+		r.Scores[event.Payee] -= event.Amount
+	}
+	r.ScoreEvents = nil
+	if r.Result != nil {
+		for i, delta := range r.Result.Payments {
+			r.Scores[i] -= delta
+		}
+	}
+	r.Finished = true
+	r.Result = &Result{
+		Dealer: r.Dealer,
+		Wind:   r.Wind,
+		Winner: -1,
+		Loser:  -1,
+		Liable: -1,
+		Voided: true,
+	}
+	r.LastActionTime = t
+	r.Events = append(r.Events, newEvent(EventVoid, r.Turn, t))
+}
+
 // Next returns a new round, setting the dealer and the prevailing wind
 // depending on the outcome of this round.
 func (r *Round) Next() (*Round, error) {
@@ -588,6 +862,7 @@ func (r *Round) End(seat int, t time.Time) error {
 		Wind:   r.Wind,
 		Winner: -1,
 		Loser:  -1,
+		Liable: -1,
 	}
 	r.LastActionTime = t
 	r.Events = append(r.Events, newEvent(EventEnd, seat, t))
@@ -603,19 +878,35 @@ func (r *Round) View(seat int) RoundView {
 			hands[i] = hand
 		} else {
 			hands[i] = hand.View()
+			if r.Finished {
+				// concealed melds are revealed at showdown
+				hands[i].Revealed = hand.Revealed
+			}
+		}
+	}
+	events := r.Events
+	if !r.Finished {
+		events = make([]Event, len(r.Events))
+		for i, event := range r.Events {
+			if event.Type == EventConcealedGang && event.Seat != seat {
+				event.Tiles = []Tile{""}
+			}
+			events[i] = event
 		}
 	}
 	return RoundView{
 		Seat:             seat,
 		Scores:           r.Scores,
 		Hands:            hands,
-		DrawsLeft:        len(r.Wall) - MinTilesLeft + 1,
-		Discards:         r.Discards,
+		DrawsLeft:        r.drawsLeft(),
+		Discards:         r.unclaimedDiscards(),
+		Rivers:           r.Rivers,
 		Wind:             r.Wind,
 		Dealer:           r.Dealer,
 		Turn:             r.Turn,
 		Phase:            r.Phase,
-		Events:           r.Events,
+		Events:           events,
+		ScoreEvents:      r.ScoreEvents,
 		Result:           r.Result,
 		LastActionTime:   r.LastActionTime.UnixNano() / 1e6,
 		ReservedDuration: r.ReservedDuration.Milliseconds(),
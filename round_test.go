@@ -1,6 +1,7 @@
 package mahjong
 
 import (
+	"encoding/json"
 	"math/rand"
 	"testing"
 	"time"
@@ -8,6 +9,14 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func river(tiles ...Tile) []DiscardedTile {
+	discards := make([]DiscardedTile, len(tiles))
+	for i, tile := range tiles {
+		discards[i].Tile = tile
+	}
+	return discards
+}
+
 func TestRound_Draw(t *testing.T) {
 	t.Run("cannot draw on wrong turn", func(t *testing.T) {
 		r := &Round{Turn: 0}
@@ -67,6 +76,7 @@ func TestRound_Draw(t *testing.T) {
 		assert.Equal(t, []Tile{TileGentlemen1, TileGentlemen2}, r.Hands[seat].Flowers)
 		assert.Equal(t, []Tile{TileBamboo1}, r.Wall)
 		assert.Equal(t, NewTileBag([]Tile{TileWindsWest, TileDots5}), r.Hands[seat].Concealed)
+		assert.Equal(t, WinningContext{FlowerReplacement: true, LastTile: true}, r.Context)
 		assert.Equal(t, seat, r.Turn)
 		assert.Equal(t, PhaseDiscard, r.Phase)
 		assert.Contains(t, r.Events, Event{
@@ -115,16 +125,20 @@ func TestRound_Discard(t *testing.T) {
 				"37七万", "13一筒", "43北风", "26五索",
 				"21九筒", "25四索", "42西风", "17五筒",
 			},
-			Turn:     seat,
-			Phase:    PhaseDiscard,
-			Discards: []Tile{TileWindsEast},
-			Hands:    [4]Hand{{Concealed: NewTileBag([]Tile{TileCharacters1, TileWindsNorth})}},
+			Turn:   seat,
+			Phase:  PhaseDiscard,
+			Rivers: [4][]DiscardedTile{0: river(TileWindsEast)},
+			Hands:  [4]Hand{{Concealed: NewTileBag([]Tile{TileCharacters1, TileWindsNorth})}},
 		}
 		now := time.Now()
 		err := r.Discard(seat, now, TileWindsNorth)
 		assert.NoError(t, err)
 		assert.Equal(t, NewTileBag([]Tile{TileCharacters1}), r.Hands[seat].Concealed)
-		assert.Equal(t, []Tile{TileWindsEast, TileWindsNorth}, r.Discards)
+		assert.Equal(t, WinningContext{LastDiscard: true}, r.Context)
+		assert.Equal(t, []DiscardedTile{
+			{Tile: TileWindsEast},
+			{Tile: TileWindsNorth, Time: timeInMillis(now)},
+		}, r.Rivers[seat])
 		assert.Equal(t, 1, r.Turn)
 		assert.Equal(t, PhaseDraw, r.Phase)
 		assert.Equal(t, []Event{{
@@ -158,28 +172,28 @@ func TestRound_Chi(t *testing.T) {
 	})
 	t.Run("cannot chi non-suited tile", func(t *testing.T) {
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDragonsRed},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileDragonsRed)},
 		}
 		err := r.Chi(0, time.Now(), "", "")
 		assert.EqualError(t, err, "invalid sequence")
 	})
 	t.Run("cannot chi with invalid sequence", func(t *testing.T) {
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileBamboo3},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileBamboo3)},
 		}
 		err := r.Chi(0, time.Now(), TileCharacters2, TileCharacters4)
 		assert.EqualError(t, err, "invalid sequence")
 	})
 	t.Run("cannot chi when missing tiles", func(t *testing.T) {
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileBamboo3},
-			Hands:    [4]Hand{{}},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileBamboo3)},
+			Hands:  [4]Hand{{}},
 		}
 		err := r.Chi(0, time.Now(), TileBamboo2, TileBamboo4)
 		assert.EqualError(t, err, "missing tiles")
@@ -190,7 +204,7 @@ func TestRound_Chi(t *testing.T) {
 		r := &Round{
 			Turn:             0,
 			Phase:            PhaseDraw,
-			Discards:         []Tile{TileBamboo4, TileBamboo3},
+			Rivers:           [4][]DiscardedTile{3: river(TileBamboo4, TileBamboo3)},
 			Hands:            [4]Hand{{Concealed: NewTileBag([]Tile{TileWindsWest, TileBamboo1, TileBamboo2})}},
 			LastActionTime:   oneSecondAgo,
 			ReservedDuration: 2 * time.Second,
@@ -210,18 +224,22 @@ func TestRound_Chi(t *testing.T) {
 	t.Run("successful chi", func(t *testing.T) {
 		now := time.Now()
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileBamboo4, TileBamboo3},
-			Hands:    [4]Hand{{Concealed: NewTileBag([]Tile{TileWindsWest, TileBamboo1, TileBamboo2})}},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileBamboo4, TileBamboo3)},
+			Hands:  [4]Hand{{Concealed: NewTileBag([]Tile{TileWindsWest, TileBamboo1, TileBamboo2})}},
 		}
 		err := r.Chi(0, now, TileBamboo1, TileBamboo2)
 		assert.NoError(t, err)
-		assert.Equal(t, []Tile{TileBamboo4}, r.Discards)
+		assert.Equal(t, []DiscardedTile{
+			{Tile: TileBamboo4},
+			{Tile: TileBamboo3, Claimed: true, ClaimedBy: 0},
+		}, r.Rivers[3])
 		assert.Equal(t, NewTileBag([]Tile{TileWindsWest}), r.Hands[0].Concealed)
 		assert.Equal(t, Melds{{
 			Type:  MeldChi,
 			Tiles: []Tile{TileBamboo1, TileBamboo2, TileBamboo3},
+			From:  3,
 		}}, r.Hands[0].Revealed)
 		assert.Equal(t, 0, r.Turn)
 		assert.Equal(t, PhaseDiscard, r.Phase)
@@ -256,10 +274,10 @@ func TestRound_Pong(t *testing.T) {
 	})
 	t.Run("cannot pong when missing tiles", func(t *testing.T) {
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDragonsRed},
-			Hands:    [4]Hand{{}},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileDragonsRed)},
+			Hands:  [4]Hand{{}},
 		}
 		err := r.Pong(0, time.Now())
 		assert.EqualError(t, err, "missing tiles")
@@ -276,19 +294,23 @@ func TestRound_Pong(t *testing.T) {
 	t.Run("successful pong", func(t *testing.T) {
 		seat := 1
 		r := &Round{
-			Turn:     3,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDots1, TileDragonsRed},
-			Hands:    [4]Hand{{}, {Concealed: NewTileBag([]Tile{TileWindsWest, TileDragonsRed, TileDragonsRed})}},
+			Turn:   3,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{2: river(TileDots1, TileDragonsRed)},
+			Hands:  [4]Hand{{}, {Concealed: NewTileBag([]Tile{TileWindsWest, TileDragonsRed, TileDragonsRed})}},
 		}
 		now := time.Now()
 		err := r.Pong(seat, now)
 		assert.NoError(t, err)
-		assert.Equal(t, []Tile{TileDots1}, r.Discards)
+		assert.Equal(t, []DiscardedTile{
+			{Tile: TileDots1},
+			{Tile: TileDragonsRed, Claimed: true, ClaimedBy: seat},
+		}, r.Rivers[2])
 		assert.Equal(t, NewTileBag([]Tile{TileWindsWest}), r.Hands[seat].Concealed)
 		assert.Equal(t, Melds{{
 			Type:  MeldPong,
 			Tiles: []Tile{TileDragonsRed},
+			From:  2,
 		}}, r.Hands[seat].Revealed)
 		assert.Equal(t, seat, r.Turn)
 		assert.Equal(t, PhaseDiscard, r.Phase)
@@ -323,10 +345,10 @@ func TestRound_GangFromDiscard(t *testing.T) {
 	})
 	t.Run("cannot gang from discard when not enough tiles", func(t *testing.T) {
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDragonsRed},
-			Hands:    [4]Hand{{Concealed: TileBag{TileDragonsRed: 2}}},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileDragonsRed)},
+			Hands:  [4]Hand{{Concealed: TileBag{TileDragonsRed: 2}}},
 		}
 		err := r.GangFromDiscard(0, time.Now())
 		assert.EqualError(t, err, "missing tiles")
@@ -343,10 +365,10 @@ func TestRound_GangFromDiscard(t *testing.T) {
 	t.Run("successful gang from discard", func(t *testing.T) {
 		seat := 1
 		r := &Round{
-			Wall:     []Tile{TileCharacters4, TileCharacters6, TileGentlemen1},
-			Turn:     3,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDots1, TileDragonsRed},
+			Wall:   []Tile{TileCharacters4, TileCharacters6, TileGentlemen1},
+			Turn:   3,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{2: river(TileDots1, TileDragonsRed)},
 			Hands: [4]Hand{{}, {
 				Flowers:   []Tile{TileCat},
 				Concealed: NewTileBag([]Tile{TileWindsWest, TileDragonsRed, TileDragonsRed, TileDragonsRed}),
@@ -355,12 +377,16 @@ func TestRound_GangFromDiscard(t *testing.T) {
 		now := time.Now()
 		err := r.GangFromDiscard(seat, now)
 		assert.NoError(t, err)
-		assert.Equal(t, []Tile{TileDots1}, r.Discards)
+		assert.Equal(t, []DiscardedTile{
+			{Tile: TileDots1},
+			{Tile: TileDragonsRed, Claimed: true, ClaimedBy: seat},
+		}, r.Rivers[2])
 		assert.Equal(t, []Tile{TileCat, TileGentlemen1}, r.Hands[seat].Flowers)
 		assert.Equal(t, NewTileBag([]Tile{TileWindsWest, TileCharacters6}), r.Hands[seat].Concealed)
 		assert.Equal(t, Melds{{
 			Type:  MeldGang,
 			Tiles: []Tile{TileDragonsRed},
+			From:  2,
 		}}, r.Hands[seat].Revealed)
 		assert.Equal(t, seat, r.Turn)
 		assert.Equal(t, PhaseDiscard, r.Phase)
@@ -418,15 +444,17 @@ func TestRound_GangFromHand(t *testing.T) {
 		err := r.GangFromHand(seat, now, TileDragonsRed)
 		assert.NoError(t, err)
 		assert.Equal(t, Melds{{
-			Type:  MeldGang,
-			Tiles: []Tile{TileDragonsRed},
+			Type:      MeldGang,
+			Tiles:     []Tile{TileDragonsRed},
+			Concealed: true,
+			From:      -1,
 		}}, r.Hands[seat].Revealed)
 		assert.Equal(t, TileBag{TileDots4: 1}, r.Hands[seat].Concealed)
 		assert.Equal(t, []Tile{TileCharacters1}, r.Wall)
 		assert.Equal(t, seat, r.Turn)
 		assert.Equal(t, PhaseDiscard, r.Phase)
 		assert.Contains(t, r.Events, Event{
-			Type:  EventGang,
+			Type:  EventConcealedGang,
 			Seat:  seat,
 			Time:  timeInMillis(now),
 			Tiles: []Tile{TileDragonsRed},
@@ -455,10 +483,11 @@ func TestRound_GangFromHand(t *testing.T) {
 			Type:  MeldGang,
 			Tiles: []Tile{TileDragonsRed},
 		}}, r.Hands[seat].Revealed)
-		assert.Equal(t, TileBag{TileDots4: 1}, r.Hands[seat].Concealed)
-		assert.Equal(t, []Tile{TileCharacters1}, r.Wall)
+		assert.Equal(t, TileBag{}, r.Hands[seat].Concealed)
+		assert.Equal(t, []Tile{TileCharacters1, TileDots4, TileCat}, r.Wall)
 		assert.Equal(t, seat, r.Turn)
-		assert.Equal(t, PhaseDiscard, r.Phase)
+		assert.Equal(t, PhaseRobGang, r.Phase)
+		assert.Equal(t, TileDragonsRed, r.RobbableTile)
 		assert.Contains(t, r.Events, Event{
 			Type:  EventGang,
 			Seat:  seat,
@@ -466,6 +495,16 @@ func TestRound_GangFromHand(t *testing.T) {
 			Tiles: []Tile{TileDragonsRed},
 		})
 		assert.Equal(t, now, r.LastActionTime)
+
+		t.Run("draws replacement tile when gang is not robbed", func(t *testing.T) {
+			err := r.Draw(seat, now)
+			assert.NoError(t, err)
+			assert.Equal(t, TileBag{TileDots4: 1}, r.Hands[seat].Concealed)
+			assert.Equal(t, []Tile{TileSeasons1, TileCat}, r.Hands[seat].Flowers)
+			assert.Equal(t, []Tile{TileCharacters1}, r.Wall)
+			assert.Equal(t, PhaseDiscard, r.Phase)
+			assert.Equal(t, Tile(""), r.RobbableTile)
+		})
 	})
 }
 
@@ -553,8 +592,11 @@ func TestRound_Hu(t *testing.T) {
 				"42西风", "42西风", "42西风",
 				"46白板", "46白板",
 			},
-			Loser:  -1,
-			Points: 1,
+			Loser:     -1,
+			Points:    1,
+			Breakdown: []Tai{{Element: ElementFlower, Points: 1}},
+			Liable:    -1,
+			Payments:  [4]int{-2, 6, -2, -2},
 		}, r.Result)
 		assert.Equal(t, now, r.LastActionTime)
 		assert.Equal(
@@ -571,9 +613,9 @@ func TestRound_Hu(t *testing.T) {
 	t.Run("successful hu from discards", func(t *testing.T) {
 		seat := 2
 		r := &Round{
-			Turn:     0,
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDragonsRed, TileDragonsWhite},
+			Turn:   0,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileDragonsRed, TileDragonsWhite)},
 			Hands: [4]Hand{{}, {},
 				{
 					Flowers:  []Tile{TileGentlemen1, TileCat},
@@ -589,7 +631,10 @@ func TestRound_Hu(t *testing.T) {
 		}
 		err := r.Hu(seat, time.Now())
 		assert.NoError(t, err)
-		assert.Equal(t, []Tile{TileDragonsRed}, r.Discards)
+		assert.Equal(t, []DiscardedTile{
+			{Tile: TileDragonsRed},
+			{Tile: TileDragonsWhite, Claimed: true, ClaimedBy: seat},
+		}, r.Rivers[3])
 		assert.True(t, r.Finished)
 		assert.Equal(t, &Result{
 			Winner: seat,
@@ -603,6 +648,12 @@ func TestRound_Hu(t *testing.T) {
 			},
 			Loser:  3,
 			Points: 2,
+			Breakdown: []Tai{
+				{Element: ElementFlower, Points: 1},
+				{Element: ElementSeatWind, Points: 1},
+			},
+			Liable:   -1,
+			Payments: [4]int{-2, -2, 8, -4},
 		}, r.Result)
 	})
 	t.Run("cannot hu again after huing", func(t *testing.T) {
@@ -619,7 +670,7 @@ func TestRound_Hu(t *testing.T) {
 		r := &Round{
 			Turn:             0,
 			Phase:            PhaseDraw,
-			Discards:         []Tile{TileDragonsRed, TileDragonsWhite},
+			Rivers:           [4][]DiscardedTile{3: river(TileDragonsRed, TileDragonsWhite)},
 			ReservedDuration: time.Second,
 			Hands: [4]Hand{{},
 				{
@@ -641,9 +692,9 @@ func TestRound_Hu(t *testing.T) {
 	t.Run("can NOT be overridden by another player with higher precedence after reserved duration", func(t *testing.T) {
 		seat := 1
 		r := &Round{
-			Turn:     0, // means seat 3 discarded
-			Phase:    PhaseDraw,
-			Discards: []Tile{TileDragonsRed, TileDragonsWhite},
+			Turn:   0, // means seat 3 discarded
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{3: river(TileDragonsRed, TileDragonsWhite)},
 			Hands: [4]Hand{
 				{},
 				// seat 1 can also hu on the same tile
@@ -683,7 +734,7 @@ func TestRound_Hu(t *testing.T) {
 		r := &Round{
 			Turn:             0, // means seat 3 discarded
 			Phase:            PhaseDraw,
-			Discards:         []Tile{TileDragonsRed, TileDragonsWhite},
+			Rivers:           [4][]DiscardedTile{3: river(TileDragonsRed, TileDragonsWhite)},
 			ReservedDuration: 2 * time.Second,
 			Hands: [4]Hand{
 				{},
@@ -724,7 +775,10 @@ func TestRound_Hu(t *testing.T) {
 			TileWindsWest, TileWindsWest, TileWindsWest,
 			TileDragonsWhite, // winning tile was removed
 		}, r.Hands[2].Finished)
-		assert.Equal(t, []Tile{TileDragonsRed}, r.Discards)
+		assert.Equal(t, []DiscardedTile{
+			{Tile: TileDragonsRed},
+			{Tile: TileDragonsWhite, Claimed: true, ClaimedBy: seat},
+		}, r.Rivers[3])
 		assert.True(t, r.Finished)
 		assert.Equal(t, &Result{
 			Winner: seat,
@@ -738,9 +792,62 @@ func TestRound_Hu(t *testing.T) {
 			},
 			Points: 2,
 			Loser:  3,
+			Breakdown: []Tai{
+				{Element: ElementFlower, Points: 1},
+				{Element: ElementDragon, Points: 1},
+			},
+			Liable:   -1,
+			Payments: [4]int{-2, 8, -2, -4},
 		}, r.Result)
 		assert.Equal(t, [4]int{-2, 8, -2, -4}, r.Scores)
 	})
+	t.Run("cannot rob own gang", func(t *testing.T) {
+		r := &Round{Turn: 0, Phase: PhaseRobGang}
+		err := r.Hu(0, time.Now())
+		assert.EqualError(t, err, "wrong turn")
+	})
+	t.Run("successful robbing the gang", func(t *testing.T) {
+		seat := 3
+		r := &Round{
+			Turn:             0,
+			Phase:            PhaseDiscard,
+			Wall:             []Tile{TileCharacters1, TileDots4},
+			ReservedDuration: 2 * time.Second,
+			Hands: [4]Hand{
+				{
+					Revealed:  []Meld{{Type: MeldPong, Tiles: []Tile{TileDots5}, From: 2}},
+					Concealed: TileBag{TileDots5: 1},
+				},
+				{},
+				{},
+				{
+					Flowers:  []Tile{},
+					Revealed: []Meld{{Type: MeldChi, Tiles: []Tile{TileDots1, TileDots2, TileDots3}}},
+					Concealed: NewTileBag([]Tile{
+						TileBamboo6, TileBamboo7, TileBamboo8,
+						TileWindsWest, TileWindsWest, TileWindsWest,
+						TileCharacters8, TileCharacters8,
+						TileDots4, TileDots6,
+					}),
+				},
+			},
+		}
+		now := time.Now()
+		_ = r.GangFromHand(0, now, TileDots5)
+
+		err := r.Hu(seat, now)
+		assert.NoError(t, err)
+		assert.True(t, r.Finished)
+		assert.Equal(t, Melds{{Type: MeldPong, Tiles: []Tile{TileDots5}, From: 2}}, r.Hands[0].Revealed)
+		assert.Equal(t, []Tile{TileCharacters1, TileDots4}, r.Wall)
+		assert.Equal(t, 0, r.Result.Loser)
+		assert.Contains(t, r.Result.Breakdown, Tai{Element: ElementRobbingTheGang, Points: 1})
+
+		t.Run("gang owner cannot draw replacement after gang is robbed", func(t *testing.T) {
+			err := r.Draw(0, now.Add(r.ReservedDuration))
+			assert.EqualError(t, err, "round finished")
+		})
+	})
 }
 
 func TestRound_View(t *testing.T) {
@@ -769,7 +876,8 @@ func TestRound_View(t *testing.T) {
 					{Flowers: []Tile{"06兰", "12冬"}, Revealed: []Meld{}, Concealed: TileBag{"": 13}},
 				},
 				DrawsLeft: len(r.Wall) - 15,
-				Discards:  r.Discards,
+				Rivers:    r.Rivers,
+				Discards:  []Tile{TileBamboo1},
 				Wind:      r.Wind,
 				Dealer:    r.Dealer,
 				Turn:      r.Turn,
@@ -808,7 +916,8 @@ func TestRound_View(t *testing.T) {
 					{Flowers: []Tile{"06兰", "12冬"}, Revealed: []Meld{}, Concealed: TileBag{"": 13}},
 				},
 				DrawsLeft: len(r.Wall) - 15,
-				Discards:  r.Discards,
+				Rivers:    r.Rivers,
+				Discards:  []Tile{TileBamboo1},
 				Wind:      r.Wind,
 				Dealer:    r.Dealer,
 				Turn:      r.Turn,
@@ -834,6 +943,191 @@ func TestRound_View(t *testing.T) {
 	})
 }
 
+func TestRound_View_concealedGang(t *testing.T) {
+	now := time.Now()
+	r := &Round{
+		Wall:  []Tile{TileCharacters1, TileDots4},
+		Turn:  0,
+		Phase: PhaseDiscard,
+		Hands: [4]Hand{{
+			Flowers:   []Tile{},
+			Revealed:  Melds{},
+			Concealed: TileBag{TileDragonsRed: 4},
+		}, {}, {}, {}},
+	}
+	_ = r.GangFromHand(0, now, TileDragonsRed)
+	t.Run("own concealed gang is visible", func(t *testing.T) {
+		view := r.View(0)
+		assert.Equal(t, r.Hands[0].Revealed, view.Hands[0].Revealed)
+		assert.Equal(t, []Tile{TileDragonsRed}, view.Events[0].Tiles)
+	})
+	t.Run("concealed gang is face down to other players", func(t *testing.T) {
+		view := r.View(1)
+		assert.Equal(t, Melds{{
+			Type:      MeldGang,
+			Tiles:     []Tile{""},
+			Concealed: true,
+			From:      -1,
+		}}, view.Hands[0].Revealed)
+		assert.Equal(t, []Tile{""}, view.Events[0].Tiles)
+		assert.Equal(t, []Tile{TileDragonsRed}, r.Events[0].Tiles)
+	})
+	t.Run("concealed gang is revealed at showdown", func(t *testing.T) {
+		r.Finished = true
+		defer func() { r.Finished = false }()
+		view := r.View(1)
+		assert.Equal(t, r.Hands[0].Revealed, view.Hands[0].Revealed)
+		assert.Equal(t, []Tile{TileDragonsRed}, view.Events[0].Tiles)
+	})
+}
+
+func TestRound_firstTurn(t *testing.T) {
+	// waiting is a hand with a dragon pong waiting on a red dragon for its
+	// eyes
+	waiting := []Tile{
+		TileDragonsWhite, TileDragonsWhite, TileDragonsWhite,
+		TileBamboo2, TileBamboo3, TileBamboo4,
+		TileBamboo5, TileBamboo6, TileBamboo7,
+		TileCharacters8, TileCharacters8, TileCharacters8,
+		TileDragonsRed,
+	}
+	start := func() *Round {
+		r := &Round{Rules: RulesDefault}
+		r.Start(1, time.Now())
+		for seat := range r.Hands {
+			r.Hands[seat] = Hand{Concealed: NewTileBag([]Tile{TileWindsNorth, TileDragonsRed})}
+		}
+		return r
+	}
+	elements := func(tai []Tai) []Element {
+		var elements []Element
+		for _, t := range tai {
+			elements = append(elements, t.Element)
+		}
+		return elements
+	}
+	t.Run("dealer wins with heavenly hand before discarding", func(t *testing.T) {
+		r := start()
+		assert.True(t, r.Context.FirstTurn)
+		r.Hands[0].Concealed = NewTileBag(append(waiting, TileDragonsRed))
+		err := r.Hu(0, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, []Tai{{Element: ElementHeavenlyHand, Points: 10}}, r.Result.Breakdown)
+	})
+	t.Run("wins on dealer's first discard with earthly hand", func(t *testing.T) {
+		r := start()
+		err := r.Discard(0, time.Now(), TileDragonsRed)
+		assert.NoError(t, err)
+		assert.True(t, r.Context.FirstTurn)
+		r.Hands[2].Concealed = NewTileBag(waiting)
+		err = r.Hu(2, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, []Tai{{Element: ElementEarthlyHand, Points: 10}}, r.Result.Breakdown)
+	})
+	t.Run("no earthly hand after the first draw", func(t *testing.T) {
+		r := start()
+		_ = r.Discard(0, time.Now(), TileWindsNorth)
+		err := r.Draw(1, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, r.Context.FirstTurn)
+		err = r.Discard(1, time.Now(), TileDragonsRed)
+		assert.NoError(t, err)
+		assert.False(t, r.Context.FirstTurn)
+		r.Hands[2].Concealed = NewTileBag(waiting)
+		err = r.Hu(2, time.Now())
+		assert.NoError(t, err)
+		assert.NotContains(t, elements(r.Result.Breakdown), ElementEarthlyHand)
+	})
+	t.Run("no heavenly or earthly hand on a non-dealer's first draw", func(t *testing.T) {
+		r := start()
+		_ = r.Discard(0, time.Now(), TileWindsNorth)
+		_ = r.Draw(1, time.Now())
+		r.Hands[1].Concealed = NewTileBag(append(waiting, TileDragonsRed))
+		err := r.Hu(1, time.Now())
+		assert.NoError(t, err)
+		assert.NotContains(t, elements(r.Result.Breakdown), ElementHeavenlyHand)
+		assert.NotContains(t, elements(r.Result.Breakdown), ElementEarthlyHand)
+	})
+	t.Run("no earthly hand on a discard after a claim", func(t *testing.T) {
+		r := start()
+		r.Hands[2].Concealed = NewTileBag([]Tile{TileWindsNorth, TileWindsNorth, TileDragonsRed})
+		_ = r.Discard(0, time.Now(), TileWindsNorth)
+		err := r.Pong(2, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, r.Context.FirstTurn)
+		err = r.Discard(2, time.Now(), TileDragonsRed)
+		assert.NoError(t, err)
+		r.Hands[3].Concealed = NewTileBag(waiting)
+		err = r.Hu(3, time.Now())
+		assert.NoError(t, err)
+		assert.NotContains(t, elements(r.Result.Breakdown), ElementEarthlyHand)
+	})
+}
+
+func TestRound_unclaimedDiscards(t *testing.T) {
+	now := time.Now()
+	r := &Round{
+		Rivers: [4][]DiscardedTile{
+			river(TileDots1, TileDots2),
+			{{Tile: TileBamboo1, Claimed: true, ClaimedBy: 2}},
+			river(TileCharacters1),
+		},
+		Events: []Event{
+			newEvent(EventStart, 0, now),
+			newEvent(EventDiscard, 0, now, TileDots1),
+			newEvent(EventDiscard, 1, now, TileBamboo1),
+			newEvent(EventPong, 2, now, TileBamboo1),
+			newEvent(EventDiscard, 2, now, TileCharacters1),
+			newEvent(EventDiscard, 0, now, TileDots2),
+		},
+	}
+	assert.Equal(t, []Tile{TileDots1, TileCharacters1, TileDots2}, r.unclaimedDiscards())
+}
+
+func TestRound_UnmarshalJSON(t *testing.T) {
+	t.Run("rebuilds rivers of rounds saved with a single list of discards", func(t *testing.T) {
+		legacy := struct {
+			Discards []Tile
+			Events   []Event
+		}{
+			Discards: []Tile{TileDots1, TileDots2},
+			Events: []Event{
+				{Type: EventStart, Seat: 0, Time: 1},
+				{Type: EventDiscard, Seat: 0, Time: 2, Tiles: []Tile{TileDots1}},
+				{Type: EventDraw, Seat: 1, Time: 3},
+				{Type: EventDiscard, Seat: 1, Time: 4, Tiles: []Tile{TileBamboo1}},
+				{Type: EventPong, Seat: 3, Time: 5, Tiles: []Tile{TileBamboo1}},
+				{Type: EventDiscard, Seat: 3, Time: 6, Tiles: []Tile{TileDots2}},
+			},
+		}
+		data, err := json.Marshal(legacy)
+		assert.NoError(t, err)
+		var r Round
+		err = json.Unmarshal(data, &r)
+		assert.NoError(t, err)
+		assert.Equal(t, [4][]DiscardedTile{
+			{{Tile: TileDots1, Time: 2}},
+			{{Tile: TileBamboo1, Time: 4, Claimed: true, ClaimedBy: 3}},
+			{},
+			{{Tile: TileDots2, Time: 6}},
+		}, r.Rivers)
+		assert.Equal(t, []Tile{TileDots1, TileDots2}, r.unclaimedDiscards())
+		assert.Equal(t, TileDots2, r.lastDiscard())
+	})
+	t.Run("keeps rivers of current rounds", func(t *testing.T) {
+		original := &Round{
+			Rivers: [4][]DiscardedTile{river(TileDots1), {}, {}, {}},
+			Events: []Event{{Type: EventDiscard, Seat: 0, Tiles: []Tile{TileDots1}}},
+		}
+		data, err := json.Marshal(original)
+		assert.NoError(t, err)
+		var r Round
+		err = json.Unmarshal(data, &r)
+		assert.NoError(t, err)
+		assert.Equal(t, original.Rivers, r.Rivers)
+	})
+}
+
 func Test_newWall(t *testing.T) {
 	r := rand.New(rand.NewSource(0))
 	got := newWall(r)
@@ -959,6 +1253,7 @@ func TestRound_End(t *testing.T) {
 			Wind:   r.Wind,
 			Winner: -1,
 			Loser:  -1,
+			Liable: -1,
 		}, r.Result)
 		assert.Equal(t, now, r.LastActionTime)
 		assert.Equal(t, []Event{{Type: EventEnd, Seat: 0, Time: timeInMillis(now)}}, r.Events)
@@ -1065,6 +1360,11 @@ func TestRound_addFlowers(t *testing.T) {
 			Tiles: []Tile{TileCat, TileRat},
 		})
 		assert.Equal(t, [4]int{6, -2, -2, -2}, r.Scores)
+		assert.Equal(t, []ScoreEvent{
+			{Reason: ScoreBitten, Payer: 1, Payee: 0, Amount: 2, Time: timeInMillis(now)},
+			{Reason: ScoreBitten, Payer: 2, Payee: 0, Amount: 2, Time: timeInMillis(now)},
+			{Reason: ScoreBitten, Payer: 3, Payee: 0, Amount: 2, Time: timeInMillis(now)},
+		}, r.ScoreEvents)
 	})
 	t.Run("cumulative bitten events", func(t *testing.T) {
 		r := &Round{
@@ -1086,4 +1386,85 @@ func TestRound_addFlowers(t *testing.T) {
 		})
 		assert.Equal(t, [4]int{18, -6, -6, -6}, r.Scores)
 	})
+	t.Run("flower set payout", func(t *testing.T) {
+		r := &Round{
+			Rules: Rules{FlowerSetPayout: 1},
+			Hands: [4]Hand{{}, {Flowers: []Tile{TileSeasons1, TileSeasons2, TileSeasons3}}},
+		}
+		now := time.Now()
+		r.addFlower(1, now, TileSeasons4)
+		assert.Equal(t, [4]int{-1, 3, -1, -1}, r.Scores)
+		assert.Len(t, r.ScoreEvents, 3)
+		assert.Equal(t, ScoreFlowerSet, r.ScoreEvents[0].Reason)
+	})
+}
+
+func TestRound_gangPayouts(t *testing.T) {
+	rules := Rules{ExposedGangPayout: 1, ConcealedGangPayout: 2}
+	t.Run("exposed gang from discard", func(t *testing.T) {
+		r := &Round{
+			Rules:  rules,
+			Wall:   []Tile{TileCharacters4, TileCharacters6},
+			Turn:   3,
+			Phase:  PhaseDraw,
+			Rivers: [4][]DiscardedTile{2: river(TileDragonsRed)},
+			Hands:  [4]Hand{{}, {Concealed: TileBag{TileDragonsRed: 3}}},
+		}
+		now := time.Now()
+		err := r.GangFromDiscard(1, now)
+		assert.NoError(t, err)
+		assert.Equal(t, [4]int{0, 3, -3, 0}, r.Scores)
+		assert.Equal(t, []ScoreEvent{
+			{Reason: ScoreExposedGang, Payer: 2, Payee: 1, Amount: 3, Time: timeInMillis(now)},
+		}, r.ScoreEvents)
+	})
+	t.Run("concealed gang", func(t *testing.T) {
+		r := &Round{
+			Rules: rules,
+			Wall:  []Tile{TileCharacters4, TileCharacters6},
+			Turn:  0,
+			Phase: PhaseDiscard,
+			Hands: [4]Hand{{Concealed: TileBag{TileDragonsRed: 4}}},
+		}
+		err := r.GangFromHand(0, time.Now(), TileDragonsRed)
+		assert.NoError(t, err)
+		assert.Equal(t, [4]int{6, -2, -2, -2}, r.Scores)
+		assert.Equal(t, ScoreConcealedGang, r.ScoreEvents[0].Reason)
+	})
+	t.Run("promoted gang is only paid for after it cannot be robbed", func(t *testing.T) {
+		r := &Round{
+			Rules: rules,
+			Wall:  []Tile{TileCharacters4, TileCharacters6},
+			Turn:  0,
+			Phase: PhaseDiscard,
+			Hands: [4]Hand{{
+				Revealed:  Melds{{Type: MeldPong, Tiles: []Tile{TileDragonsRed}, From: 2}},
+				Concealed: TileBag{TileDragonsRed: 1},
+			}},
+		}
+		now := time.Now()
+		_ = r.GangFromHand(0, now, TileDragonsRed)
+		assert.Equal(t, [4]int{}, r.Scores)
+		_ = r.Draw(0, now)
+		assert.Equal(t, [4]int{3, -1, -1, -1}, r.Scores)
+	})
+}
+
+func TestRound_Void(t *testing.T) {
+	now := time.Now()
+	r := &Round{
+		Scores: [4]int{10, -5, 0, -5},
+		Hands:  [4]Hand{{Flowers: []Tile{TileRat}}},
+	}
+	r.addFlower(0, now, TileCat)
+	r.Result = &Result{Winner: 1, Loser: -1, Payments: [4]int{-2, 6, -2, -2}}
+	r.Scores[0] -= 2
+	r.Scores[1] += 6
+	r.Scores[2] -= 2
+	r.Scores[3] -= 2
+	r.Void(now)
+	assert.Equal(t, [4]int{10, -5, 0, -5}, r.Scores)
+	assert.Empty(t, r.ScoreEvents)
+	assert.True(t, r.Finished)
+	assert.Equal(t, &Result{Winner: -1, Loser: -1, Liable: -1, Voided: true}, r.Result)
 }
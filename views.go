@@ -6,7 +6,6 @@ type RoundView struct {
 	Scores    [4]int    `json:"scores"`
 	Hands     [4]Hand   `json:"hands"`
 	DrawsLeft int       `json:"draws_left"`
-	Discards  []Tile    `json:"discards"`
 	Wind      Direction `json:"wind"`
 	Dealer    int       `json:"dealer"`
 	Turn      int       `json:"turn"`
@@ -15,6 +14,19 @@ type RoundView struct {
 	Result    *Result   `json:"result,omitempty"`
 	Finished  bool      `json:"finished"`
 
+	// ScoreEvents contains the immediate payments made during the round.
+	ScoreEvents []ScoreEvent `json:"score_events"`
+
+	// Rivers contains the tiles discarded by each player, including those
+	// which were claimed.
+	Rivers [4][]DiscardedTile `json:"rivers"`
+
+	// Discards contains the unclaimed discarded tiles in the order they were
+	// discarded.
+	//
+	// Deprecated: use Rivers instead.
+	Discards []Tile `json:"discards"`
+
 	// LastActionTime is the time the last action took place represented in milliseconds since the Unix epoch.
 	LastActionTime int64 `json:"last_action_time"`
 
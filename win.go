@@ -229,8 +229,55 @@ func isFourGreatBlessings(tiles map[Tile]int) bool {
 	return true
 }
 
-func score(round *Round, seat int, melds Melds) int {
-	score := 0
+// Element identifies a scoring element in a winning hand.
+type Element string
+
+// Possible scoring elements.
+const (
+	ElementFullFlush          Element = "full_flush"
+	ElementHalfFlush          Element = "half_flush"
+	ElementPingHu             Element = "ping_hu"
+	ElementChouPingHu         Element = "chou_ping_hu"
+	ElementPongPongHu         Element = "pong_pong_hu"
+	ElementFlower             Element = "flower"
+	ElementAnimalSet          Element = "animal_set"
+	ElementFlowerSet          Element = "flower_set"
+	ElementAllFlowers         Element = "all_flowers"
+	ElementThreeGreatScholars Element = "three_great_scholars"
+	ElementFourGreatBlessings Element = "four_great_blessings"
+	ElementThirteenWonders    Element = "thirteen_wonders"
+	ElementDragon             Element = "dragon"
+	ElementSeatWind           Element = "seat_wind"
+	ElementPrevailingWind     Element = "prevailing_wind"
+	ElementRobbingTheGang     Element = "robbing_the_gang"
+	ElementGangReplacement    Element = "gang_replacement"
+	ElementFlowerReplacement  Element = "flower_replacement"
+	ElementLastTile           Element = "last_tile"
+	ElementLastDiscard        Element = "last_discard"
+	ElementHeavenlyHand       Element = "heavenly_hand"
+	ElementEarthlyHand        Element = "earthly_hand"
+	ElementConcealedHand      Element = "concealed_hand"
+	ElementSelfDrawn          Element = "self_drawn"
+	ElementConcealedSelfDrawn Element = "concealed_self_drawn"
+)
+
+// Tai represents the points contributed by a scoring element.
+type Tai struct {
+	Element Element `json:"element"`
+	Points  int     `json:"points"`
+}
+
+func sumTai(tai []Tai) int {
+	points := 0
+	for _, t := range tai {
+		points += t.Points
+	}
+	return points
+}
+
+// tally returns the scoring elements present in a winning hand.
+func tally(round *Round, seat int, melds Melds) []Tai {
+	var tai []Tai
 	gameLimit := 10 // hard coded game limit
 	meldTypes := make(map[MeldType]int)
 	suits := make(map[Suit]int)
@@ -246,70 +293,114 @@ func score(round *Round, seat int, melds Melds) int {
 	for _, flower := range round.Hands[seat].Flowers {
 		bonusTiles[flower]++
 	}
-	// fmt.Printf("\n melds : %v \n", melds)
-	// fmt.Printf("\ntiles : %v \n", tiles)
-	// fmt.Printf("\n melds[0] : %v \n", round.Hands[seat].Flowers)
+	switch round.Phase {
+	case PhaseRobGang:
+		tai = append(tai, Tai{ElementRobbingTheGang, 1})
+	case PhaseDiscard:
+		// won by self-draw
+		if round.Context.FirstTurn && seat == round.Dealer {
+			return []Tai{{ElementHeavenlyHand, gameLimit}}
+		}
+		if round.Context.GangReplacement {
+			tai = append(tai, Tai{ElementGangReplacement, 1})
+		}
+		if round.Context.FlowerReplacement {
+			tai = append(tai, Tai{ElementFlowerReplacement, 1})
+		}
+		if round.Context.LastTile {
+			tai = append(tai, Tai{ElementLastTile, 1})
+		}
+	case PhaseDraw:
+		// won from a discard
+		if round.Context.FirstTurn && seat != round.Dealer {
+			return []Tai{{ElementEarthlyHand, gameLimit}}
+		}
+		if round.Context.LastDiscard {
+			tai = append(tai, Tai{ElementLastDiscard, 1})
+		}
+	}
+	tai = append(tai, concealmentTai(round.Rules, round.Hands[seat].isConcealed(), round.Phase == PhaseDiscard)...)
 	if isFullFlush(suits) {
-		score += 4
+		tai = append(tai, Tai{ElementFullFlush, 4})
 	} else if isHalfFlush(suits) {
-		score += 2
+		tai = append(tai, Tai{ElementHalfFlush, 2})
 	}
 	// ping hu
 	if meldTypes[MeldChi] == 4 {
 		// no flowers
 		if len(round.Hands[seat].Flowers) == 0 {
-			return score + 4
+			return append(tai, Tai{ElementPingHu, 4})
 		}
 		// chou ping hu is worth 1 point
-		score += 1
+		tai = append(tai, Tai{ElementChouPingHu, 1})
 	}
 	// pong pong hu
 	if meldTypes[MeldPong]+meldTypes[MeldGang] == 4 {
-		score += 2
+		tai = append(tai, Tai{ElementPongPongHu, 2})
 	}
 	// flowers
 	for _, flower := range round.Hands[seat].Flowers {
 		if isFlowerForSeat(flower, seat) {
-			score++
+			tai = append(tai, Tai{ElementFlower, 1})
 		}
 	}
 	if isAnimalSet(bonusTiles) {
-		score++
+		tai = append(tai, Tai{ElementAnimalSet, 1})
 	}
 
 	// Add Other Flower Conditions
 	if isFlowerSet(bonusTiles) && isSeasonSet(bonusTiles) {
-		return gameLimit
+		return []Tai{{ElementAllFlowers, gameLimit}}
 	} else if isFlowerSet(bonusTiles) || isSeasonSet(bonusTiles) {
-		score++
+		tai = append(tai, Tai{ElementFlowerSet, 1})
 	}
 	// Three Great Scholars
 	if isThreeGreatScholars(tiles) {
-		score += 2 // since the code counts 1 each again later
+		tai = append(tai, Tai{ElementThreeGreatScholars, 2}) // since the code counts 1 each again later
 	}
 	// Four Great Blessings
 	if isFourGreatBlessings(tiles) {
-		return gameLimit
+		return []Tai{{ElementFourGreatBlessings, gameLimit}}
 	}
 	// Thirteen Wonders
 	if isThirteenWonders(tiles) {
-		return gameLimit
+		return []Tai{{ElementThirteenWonders, gameLimit}}
 	}
 
 	for _, m := range melds {
 		if m.Type == MeldPong || m.Type == MeldGang {
 			if m.Tiles[0] == TileDragonsRed || m.Tiles[0] == TileDragonsGreen || m.Tiles[0] == TileDragonsWhite {
-				score++
+				tai = append(tai, Tai{ElementDragon, 1})
 			}
 			if isMatchingWind(m.Tiles[0], round.seatWind(seat)) {
-				score++
+				tai = append(tai, Tai{ElementSeatWind, 1})
 			}
 			if isMatchingWind(m.Tiles[0], round.Wind) {
-				score++
+				tai = append(tai, Tai{ElementPrevailingWind, 1})
 			}
 		}
 	}
-	return score
+	return tai
+}
+
+// concealmentTai returns the scoring elements for a winning hand with no
+// exposed melds or which was won by self-draw, depending on the rules.
+func concealmentTai(rules Rules, concealed, selfDrawn bool) []Tai {
+	if concealed && selfDrawn && rules.ConcealedSelfDrawn > 0 {
+		return []Tai{{ElementConcealedSelfDrawn, rules.ConcealedSelfDrawn}}
+	}
+	var tai []Tai
+	if concealed && rules.ConcealedHand > 0 {
+		tai = append(tai, Tai{ElementConcealedHand, rules.ConcealedHand})
+	}
+	if selfDrawn && rules.SelfDrawn > 0 {
+		tai = append(tai, Tai{ElementSelfDrawn, rules.SelfDrawn})
+	}
+	return tai
+}
+
+func score(round *Round, seat int, melds Melds) int {
+	return sumTai(tally(round, seat, melds))
 }
 
 var (
@@ -326,6 +417,36 @@ var (
 type Rules struct {
 	Shooter bool
 	Limit   int
+
+	// ConcealedHand is the number of points awarded for winning without any
+	// exposed melds, also known as men qian qing. Concealed gangs do not
+	// count as exposed.
+	ConcealedHand int
+
+	// SelfDrawn is the number of points awarded for winning by self-draw.
+	SelfDrawn int
+
+	// ConcealedSelfDrawn is the number of points awarded for winning by
+	// self-draw without any exposed melds. If set, it replaces ConcealedHand
+	// and SelfDrawn when both would apply.
+	ConcealedSelfDrawn int
+
+	// Bao makes a player who discards the tile completing a winner's third
+	// dragon pong or fourth wind pong pay on behalf of everyone.
+	Bao bool
+
+	// ExposedGangPayout is how much each other player pays immediately when
+	// a player reveals a gang formed from a pong. When a gang is formed from
+	// a discard, the discarder pays on behalf of everyone instead.
+	ExposedGangPayout int
+
+	// ConcealedGangPayout is how much each other player pays immediately
+	// when a player reveals a concealed gang.
+	ConcealedGangPayout int
+
+	// FlowerSetPayout is how much each other player pays immediately when a
+	// player collects all four gentlemen or all four seasons.
+	FlowerSetPayout int
 }
 
 // winnings returns how much each player's score changes.
@@ -367,3 +488,57 @@ func winnings(rules Rules, winner, loser, points int) [4]int {
 	}
 	return deltas
 }
+
+// liability returns the integer offset of the player who fed the tile
+// completing a third dragon pong or fourth wind pong in a winning hand, or -1
+// if there is none. The set is attributed to the loser when the winning
+// discard completes it. Otherwise, it is completed by the last of its melds
+// to be revealed, and attributed to the player whose discard was claimed for
+// that meld. Sets completed by a concealed gang or a pong in hand are not
+// attributed to anyone.
+func liability(revealed Melds, concealed Melds, winningTile Tile, loser int) int {
+	for _, honours := range [][]Tile{dragonTiles, windTiles} {
+		var last *Meld
+		count := 0
+		inHand := false
+		completedByDiscard := false
+		for i, meld := range revealed {
+			if (meld.Type == MeldPong || meld.Type == MeldGang) && contains(honours, meld.Tiles[0]) {
+				count++
+				last = &revealed[i]
+			}
+		}
+		for _, meld := range concealed {
+			if meld.Type == MeldPong && contains(honours, meld.Tiles[0]) {
+				count++
+				inHand = true
+				if loser != -1 && meld.Tiles[0] == winningTile {
+					completedByDiscard = true
+				}
+			}
+		}
+		if count != len(honours) {
+			continue
+		}
+		if completedByDiscard {
+			return loser
+		}
+		if !inHand && !last.Concealed {
+			return last.From
+		}
+	}
+	return -1
+}
+
+// payments returns how much each player's score changes. If liable is not
+// -1, that player pays the winner on behalf of everyone.
+func payments(rules Rules, winner, loser, liable, points int) [4]int {
+	deltas := winnings(rules, winner, loser, points)
+	if liable == -1 {
+		return deltas
+	}
+	var bao [4]int
+	bao[winner] = deltas[winner]
+	bao[liable] = -deltas[winner]
+	return bao
+}
@@ -1,6 +1,7 @@
 package mahjong
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -291,6 +292,121 @@ func Test_score(t *testing.T) {
 		}
 		assert.Equal(t, 3, score(round, 0, melds)) //10 is hard coded as limit
 	})
+	t.Run("heavenly hand", func(t *testing.T) {
+		round := &Round{
+			Dealer:  1,
+			Turn:    1,
+			Phase:   PhaseDiscard,
+			Context: WinningContext{FirstTurn: true},
+			Hands:   [4]Hand{{}, {}},
+		}
+		melds := []Meld{
+			{Type: MeldPong, Tiles: []Tile{TileDots1}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo3, TileBamboo4, TileBamboo5}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo2, TileBamboo3, TileBamboo4}},
+			{Type: MeldChi, Tiles: []Tile{TileDots1, TileDots2, TileDots3}},
+			{Type: MeldEyes, Tiles: []Tile{TileDots1}},
+		}
+		assert.Equal(t, []Tai{{Element: ElementHeavenlyHand, Points: 10}}, tally(round, 1, melds))
+	})
+	t.Run("earthly hand", func(t *testing.T) {
+		round := &Round{
+			Dealer:  1,
+			Turn:    2,
+			Phase:   PhaseDraw,
+			Context: WinningContext{FirstTurn: true},
+			Hands:   [4]Hand{{}, {}, {}, {}},
+		}
+		melds := []Meld{
+			{Type: MeldPong, Tiles: []Tile{TileDots1}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo3, TileBamboo4, TileBamboo5}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo2, TileBamboo3, TileBamboo4}},
+			{Type: MeldChi, Tiles: []Tile{TileDots1, TileDots2, TileDots3}},
+			{Type: MeldEyes, Tiles: []Tile{TileDots1}},
+		}
+		assert.Equal(t, []Tai{{Element: ElementEarthlyHand, Points: 10}}, tally(round, 3, melds))
+	})
+	t.Run("self-draw on replacement tile after gang and flower", func(t *testing.T) {
+		round := &Round{
+			Turn:    0,
+			Phase:   PhaseDiscard,
+			Context: WinningContext{GangReplacement: true, FlowerReplacement: true, LastTile: true},
+			Hands:   [4]Hand{{}},
+		}
+		melds := []Meld{
+			{Type: MeldGang, Tiles: []Tile{TileDots9}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo3, TileBamboo4, TileBamboo5}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo2, TileBamboo3, TileBamboo4}},
+			{Type: MeldChi, Tiles: []Tile{TileDots1, TileDots2, TileDots3}},
+			{Type: MeldEyes, Tiles: []Tile{TileDots1}},
+		}
+		assert.Equal(t, []Tai{
+			{Element: ElementGangReplacement, Points: 1},
+			{Element: ElementFlowerReplacement, Points: 1},
+			{Element: ElementLastTile, Points: 1},
+		}, tally(round, 0, melds))
+	})
+	t.Run("win on last discard", func(t *testing.T) {
+		round := &Round{
+			Turn:    2,
+			Phase:   PhaseDraw,
+			Context: WinningContext{LastDiscard: true, GangReplacement: true},
+			Hands:   [4]Hand{{}},
+		}
+		melds := []Meld{
+			{Type: MeldGang, Tiles: []Tile{TileDots9}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo3, TileBamboo4, TileBamboo5}},
+			{Type: MeldChi, Tiles: []Tile{TileBamboo2, TileBamboo3, TileBamboo4}},
+			{Type: MeldChi, Tiles: []Tile{TileDots1, TileDots2, TileDots3}},
+			{Type: MeldEyes, Tiles: []Tile{TileDots1}},
+		}
+		assert.Equal(t, []Tai{{Element: ElementLastDiscard, Points: 1}}, tally(round, 0, melds))
+	})
+}
+
+func Test_concealmentTai(t *testing.T) {
+	rules := Rules{ConcealedHand: 1, SelfDrawn: 1}
+	t.Run("concealed hand from discard", func(t *testing.T) {
+		assert.Equal(t, []Tai{{ElementConcealedHand, 1}}, concealmentTai(rules, true, false))
+	})
+	t.Run("exposed hand by self-draw", func(t *testing.T) {
+		assert.Equal(t, []Tai{{ElementSelfDrawn, 1}}, concealmentTai(rules, false, true))
+	})
+	t.Run("concealed hand by self-draw", func(t *testing.T) {
+		assert.Equal(t, []Tai{{ElementConcealedHand, 1}, {ElementSelfDrawn, 1}}, concealmentTai(rules, true, true))
+	})
+	t.Run("concealed self-drawn replaces other elements", func(t *testing.T) {
+		rules := rules
+		rules.ConcealedSelfDrawn = 3
+		assert.Equal(t, []Tai{{ElementConcealedSelfDrawn, 3}}, concealmentTai(rules, true, true))
+	})
+	t.Run("disabled by default", func(t *testing.T) {
+		assert.Empty(t, concealmentTai(RulesDefault, true, true))
+	})
+}
+
+func Test_score_concealment(t *testing.T) {
+	round := &Round{
+		Turn:  0,
+		Phase: PhaseDiscard,
+		Rules: Rules{ConcealedHand: 1, SelfDrawn: 1},
+		Hands: [4]Hand{{
+			Revealed: Melds{{Type: MeldGang, Tiles: []Tile{TileDots9}, Concealed: true, From: -1}},
+		}},
+	}
+	melds := append(round.Hands[0].Revealed, Melds{
+		{Type: MeldChi, Tiles: []Tile{TileBamboo3, TileBamboo4, TileBamboo5}},
+		{Type: MeldChi, Tiles: []Tile{TileBamboo2, TileBamboo3, TileBamboo4}},
+		{Type: MeldChi, Tiles: []Tile{TileDots1, TileDots2, TileDots3}},
+		{Type: MeldEyes, Tiles: []Tile{TileDots1}},
+	}...)
+	t.Run("concealed gang does not break concealment", func(t *testing.T) {
+		assert.Equal(t, 2, score(round, 0, melds))
+	})
+	t.Run("exposed meld breaks concealment", func(t *testing.T) {
+		round.Hands[0].Revealed[0].Concealed = false
+		assert.Equal(t, 1, score(round, 0, melds))
+	})
 }
 
 func Test_winnings(t *testing.T) {
@@ -331,3 +447,101 @@ func Test_winnings(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 }
+
+func Test_liability(t *testing.T) {
+	t.Run("third dragon pong claimed from discard", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldPong, Tiles: []Tile{TileDragonsRed}, From: 3},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsGreen}, From: 0},
+			{Type: MeldGang, Tiles: []Tile{TileDragonsWhite}, From: 2},
+		}
+		assert.Equal(t, 2, liability(revealed, nil, TileDots1, -1))
+	})
+	t.Run("winning discard completes third dragon pong", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldPong, Tiles: []Tile{TileDragonsRed}, From: 3},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsGreen}, From: 0},
+		}
+		concealed := Melds{{Type: MeldPong, Tiles: []Tile{TileDragonsWhite}}}
+		assert.Equal(t, 0, liability(revealed, concealed, TileDragonsWhite, 0))
+	})
+	t.Run("pong completed in hand", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldPong, Tiles: []Tile{TileDragonsRed}, From: 3},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsGreen}, From: 0},
+		}
+		concealed := Melds{{Type: MeldPong, Tiles: []Tile{TileDragonsWhite}}}
+		assert.Equal(t, -1, liability(revealed, concealed, "", -1))
+	})
+	t.Run("fourth wind pong claimed from discard", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldPong, Tiles: []Tile{TileWindsEast}, From: 3},
+			{Type: MeldPong, Tiles: []Tile{TileWindsSouth}, From: 0},
+			{Type: MeldPong, Tiles: []Tile{TileWindsWest}, From: 0},
+			{Type: MeldPong, Tiles: []Tile{TileWindsNorth}, From: 1},
+		}
+		assert.Equal(t, 1, liability(revealed, nil, "", -1))
+	})
+	t.Run("third dragon pong claimed after a concealed dragon gang", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldGang, Tiles: []Tile{TileDragonsRed}, Concealed: true},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsGreen}, From: 0},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsWhite}, From: 2},
+		}
+		assert.Equal(t, 2, liability(revealed, nil, TileDots1, -1))
+	})
+	t.Run("third dragon completed by a concealed gang", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldPong, Tiles: []Tile{TileDragonsGreen}, From: 0},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsWhite}, From: 2},
+			{Type: MeldGang, Tiles: []Tile{TileDragonsRed}, Concealed: true},
+		}
+		assert.Equal(t, -1, liability(revealed, nil, TileDots1, -1))
+	})
+	t.Run("only two dragon pongs", func(t *testing.T) {
+		revealed := Melds{
+			{Type: MeldPong, Tiles: []Tile{TileDragonsRed}, From: 3},
+			{Type: MeldPong, Tiles: []Tile{TileDragonsGreen}, From: 0},
+		}
+		assert.Equal(t, -1, liability(revealed, nil, "", -1))
+	})
+}
+
+func Test_payments(t *testing.T) {
+	t.Run("no liable player", func(t *testing.T) {
+		assert.Equal(t, winnings(RulesDefault, 0, 2, 3), payments(RulesDefault, 0, 2, -1, 3))
+	})
+	t.Run("liable player pays for everyone", func(t *testing.T) {
+		assert.Equal(t, [4]int{16, 0, 0, -16}, payments(RulesDefault, 0, 2, 3, 3))
+	})
+}
+
+func TestMeld_UnmarshalJSON(t *testing.T) {
+	t.Run("melds saved before from are not from any player", func(t *testing.T) {
+		var meld Meld
+		err := json.Unmarshal([]byte(`{"type": 1, "tiles": ["11一万", "11一万", "11一万"]}`), &meld)
+		assert.NoError(t, err)
+		assert.Equal(t, -1, meld.From)
+	})
+	t.Run("keeps from", func(t *testing.T) {
+		var meld Meld
+		err := json.Unmarshal([]byte(`{"type": 1, "tiles": ["11一万", "11一万", "11一万"], "from": 0}`), &meld)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, meld.From)
+	})
+}
+
+func TestResult_UnmarshalJSON(t *testing.T) {
+	t.Run("results saved before liability have no liable player", func(t *testing.T) {
+		var result Result
+		err := json.Unmarshal([]byte(`{"winner": 1, "loser": 0, "points": 3}`), &result)
+		assert.NoError(t, err)
+		assert.Equal(t, Result{Winner: 1, Loser: 0, Points: 3, Liable: -1}, result)
+	})
+	t.Run("keeps liable player", func(t *testing.T) {
+		var result Result
+		err := json.Unmarshal([]byte(`{"winner": 1, "loser": 0, "points": 10, "liable": 0}`), &result)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, result.Liable)
+	})
+}